@@ -17,9 +17,74 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"strings"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// RedirectConditionReady indicates whether the Ingress or HTTPRoute of the Redirect is synced and its TLS certificate is available
+	RedirectConditionReady = "Ready"
+	// RedirectConditionIngressSynced indicates whether the Ingress of the Redirect matches the Redirect
+	RedirectConditionIngressSynced = "IngressSynced"
+	// RedirectConditionHTTPRouteSynced indicates whether the HTTPRoute of the Redirect matches the Redirect
+	RedirectConditionHTTPRouteSynced = "HTTPRouteSynced"
+	// RedirectConditionTLSReady indicates whether the TLS certificate of the Redirect is available, if TLS is enabled
+	RedirectConditionTLSReady = "TLSReady"
+
+	// RedirectReasonReady is used when the Redirect is ready
+	RedirectReasonReady = "Ready"
+	// RedirectReasonIngressCreated is used for the Event emitted when the Ingress of the Redirect was created
+	RedirectReasonIngressCreated = "IngressCreated"
+	// RedirectReasonIngressUpdated is used for the Event emitted when the Ingress of the Redirect was updated
+	RedirectReasonIngressUpdated = "IngressUpdated"
+	// RedirectReasonIngressSynced is used when the Ingress of the Redirect matches the Redirect
+	RedirectReasonIngressSynced = "IngressSynced"
+	// RedirectReasonIngressDeleted is used for the Event emitted when a stale Ingress of the Redirect was deleted
+	RedirectReasonIngressDeleted = "IngressDeleted"
+	// RedirectReasonIngressSyncFailed is used when the Ingress of the Redirect could not be created or updated
+	RedirectReasonIngressSyncFailed = "IngressSyncFailed"
+	// RedirectReasonHTTPRouteCreated is used for the Event emitted when the HTTPRoute of the Redirect was created
+	RedirectReasonHTTPRouteCreated = "HTTPRouteCreated"
+	// RedirectReasonHTTPRouteUpdated is used for the Event emitted when the HTTPRoute of the Redirect was updated
+	RedirectReasonHTTPRouteUpdated = "HTTPRouteUpdated"
+	// RedirectReasonHTTPRouteSynced is used when the HTTPRoute of the Redirect matches the Redirect
+	RedirectReasonHTTPRouteSynced = "HTTPRouteSynced"
+	// RedirectReasonHTTPRouteDeleted is used for the Event emitted when a stale HTTPRoute of the Redirect was deleted
+	RedirectReasonHTTPRouteDeleted = "HTTPRouteDeleted"
+	// RedirectReasonHTTPRouteSyncFailed is used when the HTTPRoute of the Redirect could not be created or updated
+	RedirectReasonHTTPRouteSyncFailed = "HTTPRouteSyncFailed"
+	// RedirectReasonNoGateway is used when the HTTPRoute of the Redirect attaches to no Gateway, so nothing serves it
+	RedirectReasonNoGateway = "NoGateway"
+	// RedirectReasonTLSDisabled is used when TLS is not enabled for the Redirect
+	RedirectReasonTLSDisabled = "TLSDisabled"
+	// RedirectReasonSecretAvailable is used when the TLS Secret of the Redirect holds a certificate and key
+	RedirectReasonSecretAvailable = "SecretAvailable"
+	// RedirectReasonSecretMissing is used when the TLS Secret of the Redirect does not exist or lacks the certificate or key
+	RedirectReasonSecretMissing = "SecretMissing"
+	// RedirectReasonTLSManagedByGateway is used when the Redirect is served by an HTTPRoute, whose Gateway terminates TLS
+	RedirectReasonTLSManagedByGateway = "TLSManagedByGateway"
+)
+
+// RedirectBackend selects the kind of object that serves a Redirect
+// +kubebuilder:validation:Enum=Ingress;HTTPRoute
+type RedirectBackend string
+
+const (
+	// RedirectBackendIngress serves the Redirect by an Ingress using the ingress-nginx redirect annotations
+	RedirectBackendIngress RedirectBackend = "Ingress"
+	// RedirectBackendHTTPRoute serves the Redirect by a Gateway API HTTPRoute using a RequestRedirect filter
+	RedirectBackendHTTPRoute RedirectBackend = "HTTPRoute"
+)
+
+// RedirectIngressCodes are the codes the validating webhook admits for Redirects,
+// as only these make the browser follow the redirect to the target
+var RedirectIngressCodes = []int{301, 302, 303, 307, 308}
+
+// RedirectHTTPRouteCodes are the codes Redirects served by an HTTPRoute may use,
+// as the RequestRedirect filter of the Gateway API only supports these
+var RedirectHTTPRouteCodes = []int{301, 302}
+
 // RedirectSpec defines the desired state of Redirect.
 type RedirectSpec struct {
 	// Source is the source URL from which the redirection happens
@@ -30,7 +95,8 @@ type RedirectSpec struct {
 	// +kubebuilder:validation:Required
 	Target string `json:"target"`
 
-	// Code is the URL Code used for the redirection. Default 308
+	// Code is the URL Code used for the redirection. Default 308.
+	// The validating webhook only admits 301, 302, 303, 307 and 308, and only 301 and 302 for the HTTPRoute backend.
 	// +kubebuilder:validation:Enum=300;301;302;303;304;305;307;308
 	// +kubebuilder:default:=308
 	Code int `json:"code,omitempty"`
@@ -42,6 +108,42 @@ type RedirectSpec struct {
 	// IngressClassName makes it possible to override the ingress-class
 	// +kubebuilder:default:=nginx
 	IngressClassName string `json:"ingressClassName,omitempty"`
+
+	// Backend selects whether the Redirect is served by an Ingress or by a Gateway API HTTPRoute.
+	// If unset, the backend the controller is configured with is used.
+	// +kubebuilder:validation:Optional
+	Backend RedirectBackend `json:"backend,omitempty"`
+
+	// ParentRefs are the Gateways the HTTPRoute of the Redirect attaches to. Only used by the HTTPRoute backend.
+	// +kubebuilder:validation:Optional
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+}
+
+// ParentReference identifies a Gateway, or a listener of it, that the HTTPRoute of a Redirect attaches to
+type ParentReference struct {
+	// Group is the group of the parent. Defaults to gateway.networking.k8s.io
+	// +kubebuilder:validation:Optional
+	Group string `json:"group,omitempty"`
+
+	// Kind is the kind of the parent. Defaults to Gateway
+	// +kubebuilder:validation:Optional
+	Kind string `json:"kind,omitempty"`
+
+	// Namespace is the namespace of the parent. Defaults to the namespace of the Redirect
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the parent
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// SectionName is the name of the listener of the Gateway to attach to
+	// +kubebuilder:validation:Optional
+	SectionName string `json:"sectionName,omitempty"`
+
+	// Port is the port of the listeners of the Gateway to attach to
+	// +kubebuilder:validation:Optional
+	Port int32 `json:"port,omitempty"`
 }
 
 // TLSSpec holds the TLS configuration used
@@ -55,6 +157,16 @@ type TLSSpec struct {
 type RedirectStatus struct {
 	Target      string   `json:"target,omitempty"`
 	IngressName []string `json:"ingressNames,omitempty"`
+
+	// HTTPRouteNames are the names of the HTTPRoutes serving the Redirect
+	// +kubebuilder:validation:Optional
+	HTTPRouteNames []string `json:"httpRouteNames,omitempty"`
+
+	// Conditions represent the latest available observations of the Redirect's state
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -63,6 +175,7 @@ type RedirectStatus struct {
 // +kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source`
 // +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.spec.target`
 // +kubebuilder:printcolumn:name="Code",type=string,JSONPath=`.spec.code`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
 
 // Redirect is the Schema for the redirects API.
 type Redirect struct {
@@ -85,3 +198,27 @@ type RedirectList struct {
 func init() {
 	SchemeBuilder.Register(&Redirect{}, &RedirectList{})
 }
+
+// OverlapsSource returns true if both Redirects claim a common host. A wildcard source such as *.example.com
+// claims every host one label below example.com, just like the Ingress rule it is turned into.
+func (r *Redirect) OverlapsSource(other *Redirect) bool {
+	source := NormalizeDomain(r.Spec.Source)
+	otherSource := NormalizeDomain(other.Spec.Source)
+
+	return matchesSource(source, otherSource) || matchesSource(otherSource, source)
+}
+
+// matchesSource returns true if the source claims the host
+func matchesSource(source string, host string) bool {
+	if source == host {
+		return true
+	}
+
+	suffix, wildcard := strings.CutPrefix(source, "*")
+	if !wildcard {
+		return false
+	}
+
+	label, found := strings.CutSuffix(host, suffix)
+	return found && label != "" && !strings.Contains(label, ".")
+}
@@ -17,11 +17,73 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"regexp"
 	"slices"
+	"strings"
+	"time"
+	"unicode"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ShortlinkConditionExpired indicates whether the Shortlink expired, either by date or by click budget
+	ShortlinkConditionExpired = "Expired"
+
+	// ShortlinkReasonActive is used when the Shortlink has not expired
+	ShortlinkReasonActive = "Active"
+	// ShortlinkReasonExpiryDateReached is used when the Shortlink expired because ExpiresAt has passed
+	ShortlinkReasonExpiryDateReached = "ExpiryDateReached"
+	// ShortlinkReasonClickBudgetExhausted is used when the Shortlink expired because MaxClicks was reached
+	ShortlinkReasonClickBudgetExhausted = "ClickBudgetExhausted"
+
+	// ShortlinkConditionTargetReachable indicates whether the live target of the Shortlink answered the last health probe
+	ShortlinkConditionTargetReachable = "TargetReachable"
+
+	// ShortlinkReasonTargetResponded is used when the target answered with a successful status code
+	ShortlinkReasonTargetResponded = "TargetResponded"
+	// ShortlinkReasonTargetFailed is used when the target answered with an error status code
+	ShortlinkReasonTargetFailed = "TargetFailed"
+	// ShortlinkReasonTargetUnreachable is used when the target did not answer at all, e.g. on DNS errors or timeouts
+	ShortlinkReasonTargetUnreachable = "TargetUnreachable"
+	// ShortlinkReasonTargetNotProbed is used when the target is not probed, because the policy does not allow it
+	// or it resolves to a private address
+	ShortlinkReasonTargetNotProbed = "TargetNotProbed"
+
+	// DefaultRedirectCode is the Code of Shortlinks that do not set one
+	DefaultRedirectCode = 307
+	// DefaultRedirectAfter is the RedirectAfter of HTML redirects that do not set one, which redirect immediately
+	DefaultRedirectAfter = 0
+
+	// PasswordSecretLabel is the label Secrets need to be set to "true" to hold the password of a Shortlink
+	PasswordSecretLabel = "urlshortener.cedi.dev/password"
+	// OwnedByLoginLabel is set to "true" on Shortlinks whose owners are identified by their GitHub login.
+	// Shortlinks without it were created when owners were identified by their GitHub display name.
+	OwnedByLoginLabel = "urlshortener.cedi.dev/owned-by-login"
+)
+
+// RedirectCodes are the valid values of the Code of Shortlinks, where 200 stands for the HTML redirect
+var RedirectCodes = []int{200, 300, 301, 302, 303, 304, 305, 307, 308}
+
+// TargetPlaceholder matches the placeholders of a target template: {1}, {2}, ... for the path segments
+// following the shortlink name, {*} for all of them and {name} for the query parameter "name"
+var TargetPlaceholder = regexp.MustCompile(`\{(\*|[0-9]+|[A-Za-z_][A-Za-z0-9_.-]*)\}`)
+
+// ShortlinkVisibility defines who may follow a Shortlink
+// +kubebuilder:validation:Enum=public;authenticated;owners
+type ShortlinkVisibility string
+
+const (
+	// ShortlinkVisibilityPublic allows anyone to follow the Shortlink
+	ShortlinkVisibilityPublic ShortlinkVisibility = "public"
+	// ShortlinkVisibilityAuthenticated allows every signed-in GitHub user to follow the Shortlink
+	ShortlinkVisibilityAuthenticated ShortlinkVisibility = "authenticated"
+	// ShortlinkVisibilityOwners allows only the owner and co-owners to follow the Shortlink
+	ShortlinkVisibilityOwners ShortlinkVisibility = "owners"
+)
+
 // ShortlinkSpec defines the desired state of Shortlink.
 type ShortlinkSpec struct {
 	// Owner is the GitHub user name which created the shortlink
@@ -32,22 +94,190 @@ type ShortlinkSpec struct {
 	// +kubebuilder:validation:Optional
 	CoOwners []string `json:"owners,omitempty"`
 
-	// Target specifies the target to which we will redirect
+	// Slug is the user-facing name of the shortlink, if it is not a valid Kubernetes object name itself,
+	// such as Team_Docs or an emoji. The object is then named after a sanitized form and a hash of the slug.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[^/+]+$`
+	Slug string `json:"slug,omitempty"`
+
+	// Aliases are additional names the shortlink can be called by.
+	// Names and aliases are matched ignoring case as well as '-' and '_', and must be unique among the shortlinks served under the same domain.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:items:MinLength=1
+	// +kubebuilder:validation:items:Pattern=`^[^/+]+$`
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Path is a hierarchical name of the shortlink, such as sre/runbook, which gives teams their own sub-trees.
+	// Requests are matched to the shortlink with the longest matching path, and the remaining path is passed on.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[^/+]+(/[^/+]+)*$`
+	Path string `json:"path,omitempty"`
+
+	// Domains are the hosts the shortlink is served under, such as go.corp or s.brand.com.
+	// A shortlink without domains is served under every host, unless a shortlink of the same name is bound to the host.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:items:Pattern=`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`
+	Domains []string `json:"domains,omitempty"`
+
+	// Target specifies the target to which we will redirect.
+	// The target may contain placeholders which are filled from the request: {1}, {2}, ... for the
+	// path segments following the shortlink name, {*} for all of them and {name} for the query parameter "name",
+	// e.g. https://jira.example.com/browse/{1} or https://search.example.com?q={q}
 	// +kubebuilder:validation:Required
 	// +kubebuilder:validation:MinLength=1
 	Target string `json:"target"`
 
-	// RedirectAfter specifies after how many seconds to redirect (Default=3)
+	// NoArgsTarget is used instead of a templated Target when the shortlink is called without any arguments
+	// +kubebuilder:validation:Optional
+	NoArgsTarget string `json:"noArgsTarget,omitempty"`
+
+	// FallbackTarget is redirected to instead of the live target, Target or the target of the active schedule entry,
+	// while the last health probe found it unreachable.
+	// Without a FallbackTarget, visitors are shown that the target is currently down.
+	// +kubebuilder:validation:Optional
+	FallbackTarget string `json:"fallbackTarget,omitempty"`
+
+	// RedirectAfter specifies after how many seconds the HTML redirect (Code 200) redirects.
+	// If unset, the HTML redirect redirects immediately.
 	// +kubebuilder:default:=0
 	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:validation:Maximum=99
-	RedirectAfter int64 `json:"after,omitempty"`
+	RedirectAfter *int64 `json:"after,omitempty"`
 
 	// Code is the URL Code used for the redirection.
 	// leave on default (307) when using the HTML behavior. However, if you whish to use a HTTP 3xx redirect, set to the appropriate 3xx status code
 	// +kubebuilder:validation:Enum=200;300;301;302;303;304;305;307;308
 	// +kubebuilder:default:=307
 	Code int `json:"code,omitempty" enums:"200,300,301,302,303,304,305,307,308"`
+
+	// Variants split the traffic of the shortlink between several targets by weight.
+	// If set, a variant is picked for every request instead of using Target or Schedule.
+	// Matching Rules still take precedence over Variants.
+	// +kubebuilder:validation:Optional
+	Variants []TargetVariant `json:"variants,omitempty"`
+
+	// StickyVariants assigns a visitor the same variant on every visit using a cookie
+	// +kubebuilder:default:=false
+	StickyVariants bool `json:"stickyVariants,omitempty"`
+
+	// Rules are evaluated in order for every request. The target of the first matching rule
+	// is used instead of Target.
+	// +kubebuilder:validation:Optional
+	Rules []TargetRule `json:"rules,omitempty"`
+
+	// NotBefore is the point in time before which the shortlink is not yet active
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format:date-time
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+
+	// Schedule lists target changes that take effect at a given point in time.
+	// The latest entry whose point in time has passed overrides Target and Code.
+	// +kubebuilder:validation:Optional
+	Schedule []ScheduleEntry `json:"schedule,omitempty"`
+
+	// ExpiresAt is the point in time after which the shortlink is expired and no longer redirects
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format:date-time
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// MaxClicks is the number of invocations after which the shortlink is expired. 0 means unlimited.
+	// It is a soft limit: every replica counts its own invocations until they are written to the status,
+	// so with several replicas the budget may be exceeded by the invocations served in between.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=0
+	MaxClicks int `json:"maxClicks,omitempty"`
+
+	// DeleteAfterExpiry is the grace period after which an expired shortlink is deleted.
+	// If unset, expired shortlinks are kept.
+	// +kubebuilder:validation:Optional
+	DeleteAfterExpiry *metav1.Duration `json:"deleteAfterExpiry,omitempty"`
+
+	// PasswordSecretRef selects a key of a Secret in the namespace of the Shortlink holding the bcrypt hash
+	// of a password. If set, visitors have to enter the password before they are redirected.
+	// The Secret has to be labelled urlshortener.cedi.dev/password=true.
+	// +kubebuilder:validation:Optional
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+
+	// Visibility defines who may follow the shortlink: anyone (public), every signed-in
+	// GitHub user (authenticated) or only the owner and co-owners (owners)
+	// +kubebuilder:default:=public
+	Visibility ShortlinkVisibility `json:"visibility,omitempty"`
+
+	// Passthrough configures which parts of the request are appended to the target,
+	// e.g. to redirect go/docs/some/page to <target>/some/page
+	// +kubebuilder:validation:Optional
+	Passthrough PassthroughSpec `json:"passthrough,omitempty"`
+}
+
+// TargetVariant is one of several weighted targets of a Shortlink
+type TargetVariant struct {
+	// Name identifies the variant in the status and metrics of the Shortlink
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+$`
+	Name string `json:"name"`
+
+	// Target specifies the target to which we will redirect if this variant is picked
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Target string `json:"target"`
+
+	// Weight is the relative share of the traffic this variant receives
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	Weight int `json:"weight,omitempty"`
+}
+
+// TargetRule redirects requests matching a CEL expression to a different target
+type TargetRule struct {
+	// Expression is a CEL expression that is evaluated against the request and must return a bool.
+	// It can use the variables headers, language, userAgent, ip, path, query and now,
+	// e.g. language == 'de' or cidr('10.0.0.0/8').containsIP(ip)
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Target specifies the target to which we will redirect if the expression matches
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Target string `json:"target"`
+
+	// Code is the URL Code used for the redirection if the expression matches.
+	// If unset, the Code of the Shortlink is used.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=200;300;301;302;303;304;305;307;308
+	Code int `json:"code,omitempty" enums:"200,300,301,302,303,304,305,307,308"`
+}
+
+// ScheduleEntry is a target change that takes effect at a given point in time
+type ScheduleEntry struct {
+	// At is the point in time from which on this entry is active
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Format:date-time
+	At metav1.Time `json:"at"`
+
+	// Target specifies the target to which we will redirect while this entry is active
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Target string `json:"target"`
+
+	// Code is the URL Code used for the redirection while this entry is active.
+	// If unset, the Code of the Shortlink is used.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=200;300;301;302;303;304;305;307;308
+	Code int `json:"code,omitempty" enums:"200,300,301,302,303,304,305,307,308"`
+}
+
+// PassthroughSpec configures which parts of the request are passed through to the target
+type PassthroughSpec struct {
+	// Path appends the path following the shortlink name to the target
+	// +kubebuilder:default:=false
+	Path bool `json:"path,omitempty"`
+
+	// Query appends the query string of the request to the target
+	// +kubebuilder:default:=false
+	Query bool `json:"query,omitempty"`
 }
 
 // ShortlinkStatus defines the observed state of Shortlink.
@@ -57,6 +287,10 @@ type ShortlinkStatus struct {
 	// +kubebuilder:validation:Minimum=0
 	Count int `json:"count"`
 
+	// VariantCounts represents how often each variant of the ShortLink has been called
+	// +kubebuilder:validation:Optional
+	VariantCounts map[string]int `json:"variantCounts,omitempty"`
+
 	//LastModified is a date-time when the ShortLink was last modified
 	// +kubebuilder:validation:Format:date-time
 	// +kubebuilder:validation:Optional
@@ -65,6 +299,34 @@ type ShortlinkStatus struct {
 	// ChangedBy indicates who (GitHub User) changed the Shortlink last
 	// +kubebuilder:validation:Optional
 	ChangedBy string `json:"changedby"`
+
+	// CurrentTarget is the target the Shortlink currently redirects to
+	// +kubebuilder:validation:Optional
+	CurrentTarget string `json:"currentTarget,omitempty"`
+
+	// NextTarget is the target the Shortlink redirects to after the next scheduled change
+	// +kubebuilder:validation:Optional
+	NextTarget string `json:"nextTarget,omitempty"`
+
+	// NextTransition is the point in time of the next scheduled change
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format:date-time
+	NextTransition *metav1.Time `json:"nextTransition,omitempty"`
+
+	// TargetStatusCode is the HTTP status code the target answered the last health probe with
+	// +kubebuilder:validation:Optional
+	TargetStatusCode int `json:"targetStatusCode,omitempty"`
+
+	// TargetCheckedAt is the point in time of the last health probe
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Format:date-time
+	TargetCheckedAt *metav1.Time `json:"targetCheckedAt,omitempty"`
+
+	// Conditions represent the latest available observations of the Shortlink's state
+	// +listType=map
+	// +listMapKey=type
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -103,10 +365,190 @@ func init() {
 // ShortLinkAPI is the API representation of a Shortlink.
 type ShortLinkAPI struct {
 	Name   string          `json:"name"`
+	URL    string          `json:"url,omitempty"`
 	Spec   ShortlinkSpec   `json:"spec,omitempty"`
 	Status ShortlinkStatus `json:"status,omitempty"`
 }
 
-func (s *Shortlink) IsOwnedBy(username string) bool {
+// IsOwnedBy returns true if the GitHub user with the given login and display name is the owner or a co-owner of the Shortlink.
+// The display name is only matched for Shortlinks without the OwnedByLoginLabel, which were created when owners were
+// identified by their display name. Anyone can choose any display name, so it must never match a login.
+func (s *Shortlink) IsOwnedBy(login string, displayName string) bool {
+	if s.isOwner(login) {
+		return true
+	}
+
+	return displayName != "" && s.Labels[OwnedByLoginLabel] != "true" && s.isOwner(displayName)
+}
+
+func (s *Shortlink) isOwner(username string) bool {
 	return s.Spec.Owner == username || slices.Contains(s.Spec.CoOwners, username)
 }
+
+// Names returns the name, the slug, all aliases and the path of the Shortlink
+func (s *Shortlink) Names() []string {
+	names := []string{s.Name}
+	if s.Spec.Slug != "" {
+		names = append(names, s.Spec.Slug)
+	}
+
+	names = append(names, s.Spec.Aliases...)
+	if s.Spec.Path != "" {
+		names = append(names, s.Spec.Path)
+	}
+
+	return names
+}
+
+// ConflictingName returns the first name or alias of the Shortlink that matches a name or alias
+// of the other Shortlink served under the same domain, or an empty string if there is none
+func (s *Shortlink) ConflictingName(other *Shortlink) string {
+	if !s.SharesDomain(other) {
+		return ""
+	}
+
+	otherNames := make(map[string]bool)
+	for _, name := range other.Names() {
+		otherNames[NormalizeShortlinkName(name)] = true
+	}
+
+	for _, name := range s.Names() {
+		if otherNames[NormalizeShortlinkName(name)] {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// NormalizeShortlinkName returns the form of a shortlink name or alias that is used for matching,
+// ignoring case as well as '-' and '_', so go/Onboarding, go/onboarding and go/on-boarding are the same link
+func NormalizeShortlinkName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' {
+			return -1
+		}
+
+		return unicode.ToLower(r)
+	}, name)
+}
+
+// IsTargetTemplate returns true if target contains placeholders
+func IsTargetTemplate(target string) bool {
+	return TargetPlaceholder.MatchString(target)
+}
+
+// IsTargetReachable returns false if the last health probe found the target of the Shortlink unreachable.
+// Targets that were not probed yet are considered reachable.
+func (s *Shortlink) IsTargetReachable() bool {
+	return !meta.IsStatusConditionFalse(s.Status.Conditions, ShortlinkConditionTargetReachable)
+}
+
+// ServesDomain returns true if the Shortlink is served under the given host
+func (s *Shortlink) ServesDomain(host string) bool {
+	return len(s.Spec.Domains) == 0 || slices.Contains(s.Spec.Domains, NormalizeDomain(host))
+}
+
+// SharesDomain returns true if both Shortlinks are served under a common domain. Shortlinks bound to
+// domains take precedence over the ones without, so only Shortlinks of the same kind can share a domain.
+func (s *Shortlink) SharesDomain(other *Shortlink) bool {
+	if len(s.Spec.Domains) == 0 || len(other.Spec.Domains) == 0 {
+		return len(s.Spec.Domains) == len(other.Spec.Domains)
+	}
+
+	return slices.ContainsFunc(s.Spec.Domains, other.ServesDomain)
+}
+
+// NormalizeDomain returns the domain of a Host header, lowercased and without port or trailing dot
+func NormalizeDomain(host string) string {
+	if idx := strings.LastIndexByte(host, ':'); idx >= 0 && !strings.Contains(host[idx:], "]") {
+		host = host[:idx]
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// RedirectDelay returns after how many seconds the HTML redirect of the Shortlink redirects
+func (s *Shortlink) RedirectDelay() int64 {
+	if s.Spec.RedirectAfter == nil {
+		return DefaultRedirectAfter
+	}
+
+	return *s.Spec.RedirectAfter
+}
+
+// IsVisibleTo returns true if the GitHub user with the given login and display name may follow the Shortlink.
+// An empty login stands for an anonymous visitor.
+func (s *Shortlink) IsVisibleTo(login string, displayName string) bool {
+	switch s.Spec.Visibility {
+	case ShortlinkVisibilityAuthenticated:
+		return login != ""
+	case ShortlinkVisibilityOwners:
+		return login != "" && s.IsOwnedBy(login, displayName)
+	default:
+		return true
+	}
+}
+
+// IsActive returns false if the Shortlink is not yet active at the given point in time
+func (s *Shortlink) IsActive(now time.Time) bool {
+	return s.Spec.NotBefore == nil || !now.Before(s.Spec.NotBefore.Time)
+}
+
+// ActiveTarget returns the target and code that are active at the given point in time,
+// taking the schedule of the Shortlink into account
+func (s *Shortlink) ActiveTarget(now time.Time) (string, int) {
+	target, code := s.Spec.Target, s.Spec.Code
+
+	var activeSince time.Time
+	for _, entry := range s.Spec.Schedule {
+		if now.Before(entry.At.Time) || entry.At.Time.Before(activeSince) {
+			continue
+		}
+
+		activeSince = entry.At.Time
+		target = entry.Target
+		code = s.Spec.Code
+		if entry.Code != 0 {
+			code = entry.Code
+		}
+	}
+
+	return target, code
+}
+
+// NextScheduleEntry returns the first schedule entry that becomes active after the given point in time,
+// or nil if no further target changes are scheduled
+func (s *Shortlink) NextScheduleEntry(now time.Time) *ScheduleEntry {
+	var next *ScheduleEntry
+	for idx, entry := range s.Spec.Schedule {
+		if !entry.At.Time.After(now) {
+			continue
+		}
+
+		if next == nil || entry.At.Time.Before(next.At.Time) {
+			next = &s.Spec.Schedule[idx]
+		}
+	}
+
+	return next
+}
+
+// ExpiryReason returns the reason why the Shortlink is expired at the given point in time,
+// or an empty string if it is not expired
+func (s *Shortlink) ExpiryReason(now time.Time) string {
+	if s.Spec.ExpiresAt != nil && !now.Before(s.Spec.ExpiresAt.Time) {
+		return ShortlinkReasonExpiryDateReached
+	}
+
+	if s.Spec.MaxClicks > 0 && s.Status.Count >= s.Spec.MaxClicks {
+		return ShortlinkReasonClickBudgetExhausted
+	}
+
+	return ""
+}
+
+// IsExpired returns true if the Shortlink is expired at the given point in time
+func (s *Shortlink) IsExpired(now time.Time) bool {
+	return s.ExpiryReason(now) != ""
+}
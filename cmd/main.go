@@ -18,6 +18,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"flag"
@@ -25,6 +26,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -33,8 +35,11 @@ import (
 	"github.com/spechtlabs/go-otel-utils/otelprovider"
 	"github.com/spechtlabs/go-otel-utils/otelzap"
 	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -47,7 +52,11 @@ import (
 
 	urlshortenerv1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
 	"github.com/spechtlabs/urlshortener/internal/controller"
+	webhookv1alpha1 "github.com/spechtlabs/urlshortener/internal/webhook/v1alpha1"
 	apiController "github.com/spechtlabs/urlshortener/pkg/api"
+	shortlinkClient "github.com/spechtlabs/urlshortener/pkg/client"
+	"github.com/spechtlabs/urlshortener/pkg/policy"
+	"github.com/spechtlabs/urlshortener/pkg/slug"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -70,6 +79,17 @@ func main() {
 	var webhookCertPath, webhookCertName, webhookCertKey string
 	var enableLeaderElection bool
 	var probeAddr string
+	var apiAddr string
+	var trustedProxies string
+	var invocationFlushInterval time.Duration
+	var cookieSecretFile string
+	var githubClientID, githubClientSecretFile, githubRedirectURL string
+	var slugStrategy string
+	var redirectBackend string
+	var targetPolicyFile, targetPolicyConfigMap string
+	var targetProbeInterval, targetProbeTimeout time.Duration
+	var targetProbeConcurrency int
+	var slugLength int
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var tlsOpts []func(*tls.Config)
@@ -77,6 +97,8 @@ func main() {
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&apiAddr, "api-bind-address", ":8080", "The address the shortlink redirect and API server binds to.")
+	flag.StringVar(&trustedProxies, "trusted-proxies", "", "Comma-separated addresses or CIDRs of the proxies in front of the API server, whose X-Forwarded-For and X-Real-IP headers name the client. If not set, the client is the remote address of the connection.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.BoolVar(&secureMetrics, "metrics-secure", true, "If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.StringVar(&webhookCertPath, "webhook-cert-path", "", "The directory that contains the webhook certificate.")
@@ -86,6 +108,19 @@ func main() {
 	flag.StringVar(&metricsCertName, "metrics-cert-name", "tls.crt", "The name of the metrics server certificate file.")
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false, "If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&invocationFlushInterval, "invocation-flush-interval", 10*time.Second, "How often buffered shortlink invocation counts are written to the Shortlink status.")
+	flag.StringVar(&cookieSecretFile, "cookie-secret-file", "", "The file that contains the key used to sign cookies. If not set, a random key is generated, so cookies neither survive restarts nor work across replicas.")
+	flag.StringVar(&githubClientID, "github-oauth-client-id", "", "The client ID of the GitHub OAuth app used to sign in to non-public shortlinks. If not set, non-public shortlinks cannot be followed.")
+	flag.StringVar(&githubClientSecretFile, "github-oauth-client-secret-file", "", "The file that contains the client secret of the GitHub OAuth app.")
+	flag.StringVar(&githubRedirectURL, "github-oauth-redirect-url", "", "The URL GitHub redirects to after signing in, e.g. https://go.example.com/_/callback. If not set, it is derived from the request.")
+	flag.DurationVar(&targetProbeInterval, "target-probe-interval", 0, "How often the target of every shortlink is probed for reachability. If 0, targets are not probed.")
+	flag.DurationVar(&targetProbeTimeout, "target-probe-timeout", 10*time.Second, "How long a single target probe request may take before the target counts as unreachable.")
+	flag.IntVar(&targetProbeConcurrency, "target-probe-concurrency", 5, "How many targets are probed at the same time.")
+	flag.StringVar(&targetPolicyFile, "target-policy-file", "", "The YAML file with the policy restricting the targets users may set. If neither a file nor a ConfigMap is set, only the default policy applies, which blocks dangerous schemes, private hosts and homograph domains.")
+	flag.StringVar(&targetPolicyConfigMap, "target-policy-configmap", "", "The ConfigMap holding the target policy under the key policy.yaml, as namespace/name.")
+	flag.StringVar(&slugStrategy, "slug-strategy", string(slug.StrategyBase36), "How slugs are generated for shortlinks created without a name: base36 for random lowercase letters and digits, words for random readable words, or hash for a hash of the target.")
+	flag.IntVar(&slugLength, "slug-length", 0, "The number of characters of generated base36 and hash slugs, or the number of words of generated words slugs. If 0, 7 characters or 3 words are used.")
+	flag.StringVar(&redirectBackend, "redirect-backend", string(urlshortenerv1alpha1.RedirectBackendIngress), "What serves Redirects that do not select a backend themselves: Ingress for an ingress-nginx Ingress, or HTTPRoute for a Gateway API HTTPRoute.")
 	flag.BoolVar(&debug, "debug", false, "Turn on debug logging")
 
 	flag.Parse()
@@ -276,17 +311,84 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controller.NewRedirectReconciler(mgr.GetClient(), mgr.GetScheme()).SetupWithManager(mgr); err != nil {
+	if invocationFlushInterval <= 0 {
+		setupLog.Error(fmt.Errorf("invocation flush interval must be positive, got %s", invocationFlushInterval), "invalid invocation counter configuration")
+		os.Exit(1)
+	}
+
+	defaultRedirectBackend := urlshortenerv1alpha1.RedirectBackend(redirectBackend)
+	if defaultRedirectBackend != urlshortenerv1alpha1.RedirectBackendIngress && defaultRedirectBackend != urlshortenerv1alpha1.RedirectBackendHTTPRoute {
+		setupLog.Error(fmt.Errorf("unknown redirect backend %s", redirectBackend), "invalid redirect backend configuration")
+		os.Exit(1)
+	}
+
+	if err = controller.NewRedirectReconciler(mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme(), mgr.GetEventRecorderFor("redirect-controller"), defaultRedirectBackend).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Redirect")
 		os.Exit(1)
 	}
 
-	if err = controller.NewShortLinkReconciler(mgr.GetClient(), mgr.GetScheme()).SetupWithManager(mgr); err != nil {
+	var targetPolicy *policy.Policy
+	switch {
+	case targetPolicyFile != "":
+		targetPolicy, err = policy.Load(targetPolicyFile)
+
+	case targetPolicyConfigMap != "":
+		namespace, name, found := strings.Cut(targetPolicyConfigMap, "/")
+		if !found {
+			err = fmt.Errorf("expected namespace/name, got %q", targetPolicyConfigMap)
+			break
+		}
+
+		targetPolicy, err = policy.LoadConfigMap(ctx, mgr.GetAPIReader(), types.NamespacedName{Namespace: namespace, Name: name})
+
+	default:
+		targetPolicy, err = policy.New(policy.Config{})
+	}
+
+	if err != nil {
+		setupLog.Error(err, "unable to load target policy")
+		os.Exit(1)
+	}
+
+	var targetProber *controller.TargetProber
+	if targetProbeInterval > 0 {
+		targetProber = controller.NewTargetProber(targetProbeInterval, targetProbeTimeout, targetProbeConcurrency, targetPolicy)
+	}
+
+	if err = controller.NewShortLinkReconciler(mgr.GetClient(), mgr.GetScheme(), targetProber).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Shortlink")
 		os.Exit(1)
 	}
+	// nolint:goconst
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = webhookv1alpha1.SetupShortlinkWebhookWithManager(mgr, targetPolicy); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Shortlink")
+			os.Exit(1)
+		}
+		if err = webhookv1alpha1.SetupRedirectWebhookWithManager(mgr, defaultRedirectBackend); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Redirect")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	if err := shortlinkClient.SetupSlugIndex(ctx, mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to set up shortlink slug index")
+		os.Exit(1)
+	}
+
+	shortlinkResolver := controller.NewShortlinkResolver(mgr.GetCache())
+	if err := mgr.Add(shortlinkResolver); err != nil {
+		setupLog.Error(err, "unable to add shortlink resolver to manager")
+		os.Exit(1)
+	}
+
+	invocationCounter := shortlinkClient.NewInvocationCounter(shortlinkClient.NewShortlinkClientWithReader(mgr.GetClient(), mgr.GetAPIReader()), invocationFlushInterval)
+	if err := mgr.Add(invocationCounter); err != nil {
+		setupLog.Error(err, "unable to add invocation counter to manager")
+		os.Exit(1)
+	}
+
 	if metricsCertWatcher != nil {
 		setupLog.Info("Adding metrics certificate watcher to manager")
 		if err := mgr.Add(metricsCertWatcher); err != nil {
@@ -311,23 +413,91 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
-
-	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "problem running manager")
+	if err := mgr.AddReadyzCheck("shortlink-resolver", shortlinkResolver.ReadyzCheck); err != nil {
+		setupLog.Error(err, "unable to set up shortlink resolver ready check")
 		os.Exit(1)
 	}
 
+	// The manager runs alongside the API server, as the API server resolves shortlinks
+	// from the manager's informer cache. It is stopped only after the API server shut down.
+	mgrCtx, cancelMgr := context.WithCancel(context.Background())
+	defer cancelMgr()
+
+	mgrDone := make(chan struct{})
+	go func() {
+		defer close(mgrDone)
+
+		setupLog.Info("starting manager")
+		if err := mgr.Start(mgrCtx); err != nil {
+			setupLog.Error(err, "problem running manager")
+			cancelCtx(err)
+		}
+	}()
+
 	if debug {
 		gin.SetMode(gin.DebugMode)
 	} else {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	cookieKey := make([]byte, apiController.MinCookieKeyLength)
+	if cookieSecretFile != "" {
+		if cookieKey, err = os.ReadFile(cookieSecretFile); err != nil {
+			setupLog.Error(err, "unable to read cookie secret")
+			os.Exit(1)
+		}
+
+		// Secrets often end with a newline, which must not weaken or change the key
+		cookieKey = []byte(strings.TrimSpace(string(cookieKey)))
+		if len(cookieKey) < apiController.MinCookieKeyLength {
+			setupLog.Error(fmt.Errorf("cookie secret has %d bytes, need at least %d", len(cookieKey), apiController.MinCookieKeyLength),
+				"cookie secret is too short", "file", cookieSecretFile)
+			os.Exit(1)
+		}
+	} else {
+		// Every replica generates its own key, so cookies signed by one replica are rejected by all others
+		otelzap.L().Warn("no cookie secret configured, generating a random one: logins and unlocked shortlinks " +
+			"do not survive restarts and break with more than one replica, set --cookie-secret-file to share the key")
+		if _, err := rand.Read(cookieKey); err != nil {
+			setupLog.Error(err, "unable to generate cookie secret")
+			os.Exit(1)
+		}
+	}
+
+	var oauthConfig *oauth2.Config
+	if githubClientID != "" {
+		githubClientSecret, err := os.ReadFile(githubClientSecretFile)
+		if err != nil {
+			setupLog.Error(err, "unable to read GitHub OAuth client secret")
+			os.Exit(1)
+		}
+
+		oauthConfig = &oauth2.Config{
+			ClientID:     githubClientID,
+			ClientSecret: strings.TrimSpace(string(githubClientSecret)),
+			Endpoint:     github.Endpoint,
+			RedirectURL:  githubRedirectURL,
+			Scopes:       []string{"read:user"},
+		}
+	}
+
+	slugGenerator, err := slug.NewGenerator(slug.Strategy(slugStrategy), slugLength)
+	if err != nil {
+		setupLog.Error(err, "invalid slug generator configuration")
+		os.Exit(1)
+	}
+
+	var proxies []string
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
 	setupLog.Info("starting API server")
-	srv := apiController.NewGinGonicHTTPServer(mgr.GetClient())
+	srv := apiController.NewGinGonicHTTPServer(mgr.GetClient(), mgr.GetAPIReader(), shortlinkResolver, invocationCounter, cookieKey, oauthConfig, slugGenerator, targetPolicy, proxies)
 	srv.Load()
-	srv.ServeAsync(probeAddr)
+	srv.ServeAsync(apiAddr)
 
 	// setup stop signal handlers
 	sigs := make(chan os.Signal, 1)
@@ -372,6 +542,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Stop the manager and wait for its runnables to finish
+	cancelMgr()
+	<-mgrDone
+
 	// Wait for context cancel
 	if err := ctx.Err(); !errors.Is(err, context.Canceled) {
 		otelzap.L().WithError(err).Fatal("Exiting")
@@ -1,5 +1,4 @@
-// Package docs GENERATED BY SWAG; DO NOT EDIT
-// This file was generated by swaggo/swag
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
 package docs
 
 import "github.com/swaggo/swag"
@@ -12,8 +11,8 @@ const docTemplate = `{
         "title": "{{.Title}}",
         "contact": {
             "name": "Cedric Specht",
-            "url": "cedi.dev",
-            "email": "urlshortener@cedi.dev"
+            "url": "specht-labs.de",
+            "email": "urlshortener@specht-labs.de"
         },
         "license": {
             "name": "Apache 2.0",
@@ -24,30 +23,34 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/shortlink/": {
+        "/_/callback": {
             "get": {
-                "security": [
+                "description": "exchange the GitHub authorization code for a browser session",
+                "tags": [
+                    "default"
+                ],
+                "summary": "GitHub login callback",
+                "parameters": [
                     {
-                        "bearerAuth": []
+                        "type": "string",
+                        "description": "authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "OAuth state",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
                     }
                 ],
-                "description": "list shortlinks",
-                "produces": [
-                    "text/plain",
-                    "application/json"
-                ],
-                "tags": [
-                    "api/v1/"
-                ],
-                "summary": "list shortlinks",
                 "responses": {
-                    "200": {
-                        "description": "Success",
+                    "302": {
+                        "description": "Found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/api.ShortLink"
-                            }
+                            "type": "integer"
                         }
                     },
                     "401": {
@@ -71,36 +74,53 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/shortlink/{shortlink}": {
-            "get": {
-                "security": [
-                    {
-                        "bearerAuth": []
-                    }
+        "/_/create": {
+            "post": {
+                "description": "create a missing shortlink with the signed-in user as owner",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
                 ],
-                "description": "get a shortlink",
                 "produces": [
-                    "text/plain",
-                    "application/json"
+                    "text/html"
                 ],
                 "tags": [
-                    "api/v1/"
+                    "default"
                 ],
-                "summary": "get a shortlink",
+                "summary": "create a shortlink from the 404 page",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "home",
-                        "description": "the shortlink URL part (shortlink id)",
-                        "name": "shortlink",
-                        "in": "path"
+                        "description": "shortlink id",
+                        "name": "name",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "target of the shortlink",
+                        "name": "target",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "token of the form, bound to the browser session",
+                        "name": "csrfToken",
+                        "in": "formData",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Success",
+                    "303": {
+                        "description": "SeeOther",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
                         "schema": {
-                            "$ref": "#/definitions/api.ShortLink"
+                            "type": "integer"
                         }
                     },
                     "401": {
@@ -109,6 +129,49 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/_/login": {
+            "get": {
+                "description": "redirect to GitHub to sign in and come back to the given path afterwards",
+                "tags": [
+                    "default"
+                ],
+                "summary": "sign in with GitHub",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "path to return to after signing in",
+                        "name": "redirect",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "404": {
                         "description": "NotFound",
                         "schema": {
@@ -122,17 +185,41 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/_/logout": {
+            "get": {
+                "description": "end the browser session and return to the given path",
+                "tags": [
+                    "default"
+                ],
+                "summary": "sign out",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "path to return to after signing out",
+                        "name": "redirect",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlink/": {
+            "get": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "update a new shortlink",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "list shortlinks",
                 "produces": [
                     "text/plain",
                     "application/json"
@@ -140,31 +227,23 @@ const docTemplate = `{
                 "tags": [
                     "api/v1/"
                 ],
-                "summary": "update existing shortlink",
+                "summary": "list shortlinks",
                 "parameters": [
                     {
                         "type": "string",
-                        "example": "home",
-                        "description": "the shortlink URL part (shortlink id)",
-                        "name": "shortlink",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "shortlink spec",
-                        "name": "spec",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/v1alpha1.ShortLinkSpec"
-                        }
+                        "description": "only list the shortlinks served under this domain",
+                        "name": "domain",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Success",
                         "schema": {
-                            "type": "integer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI"
+                            }
                         }
                     },
                     "401": {
@@ -193,33 +272,25 @@ const docTemplate = `{
                         "bearerAuth": []
                     }
                 ],
-                "description": "create a new shortlink",
+                "description": "create a new shortlink with a collision-free slug generated by the server",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "text/plain",
                     "application/json"
                 ],
                 "tags": [
                     "api/v1/"
                 ],
-                "summary": "create new shortlink",
+                "summary": "create new shortlink with a generated slug",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "example": "home",
-                        "description": "the shortlink URL part (shortlink id)",
-                        "name": "shortlink",
-                        "in": "path"
-                    },
                     {
                         "description": "shortlink spec",
                         "name": "spec",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/v1alpha1.ShortLinkSpec"
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec"
                         }
                     }
                 ],
@@ -227,29 +298,11 @@ const docTemplate = `{
                     "200": {
                         "description": "Success",
                         "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "301": {
-                        "description": "MovedPermanently",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "302": {
-                        "description": "Found",
-                        "schema": {
-                            "type": "integer"
-                        }
-                    },
-                    "307": {
-                        "description": "TemporaryRedirect",
-                        "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI"
                         }
                     },
-                    "308": {
-                        "description": "PermanentRedirect",
+                    "400": {
+                        "description": "BadRequest",
                         "schema": {
                             "type": "integer"
                         }
@@ -260,8 +313,8 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
-                    "404": {
-                        "description": "NotFound",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "integer"
                         }
@@ -273,14 +326,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/shortlink/{shortlink}": {
+            "get": {
                 "security": [
                     {
                         "bearerAuth": []
                     }
                 ],
-                "description": "delete shortlink",
+                "description": "get a shortlink",
                 "produces": [
                     "text/plain",
                     "application/json"
@@ -288,7 +343,7 @@ const docTemplate = `{
                 "tags": [
                     "api/v1/"
                 ],
-                "summary": "delete shortlink",
+                "summary": "get a shortlink",
                 "parameters": [
                     {
                         "type": "string",
@@ -297,13 +352,19 @@ const docTemplate = `{
                         "name": "shortlink",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the domain the shortlink is served under, if the name is used under several domains",
+                        "name": "domain",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "Success",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI"
                         }
                     },
                     "401": {
@@ -318,6 +379,12 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "500": {
                         "description": "InternalServerError",
                         "schema": {
@@ -325,25 +392,48 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/{shortlink}": {
-            "get": {
-                "description": "redirect to target as per configuration of the shortlink",
+            },
+            "put": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "update a new shortlink",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
-                    "text/html"
+                    "text/plain",
+                    "application/json"
                 ],
                 "tags": [
-                    "default"
+                    "api/v1/"
                 ],
-                "summary": "redirect to target",
+                "summary": "update existing shortlink",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "shortlink id",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
                         "name": "shortlink",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the domain the shortlink is served under, if the name is used under several domains",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "description": "shortlink spec",
+                        "name": "spec",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec"
+                        }
                     }
                 ],
                 "responses": {
@@ -353,37 +443,734 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
-                    "300": {
-                        "description": "MultipleChoices",
+                    "400": {
+                        "description": "BadRequest",
                         "schema": {
                             "type": "integer"
                         }
                     },
-                    "301": {
-                        "description": "MovedPermanently",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "integer"
                         }
                     },
-                    "302": {
-                        "description": "Found",
+                    "404": {
+                        "description": "NotFound",
                         "schema": {
                             "type": "integer"
                         }
                     },
-                    "303": {
-                        "description": "SeeOther",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "integer"
                         }
                     },
-                    "304": {
-                        "description": "NotModified",
+                    "500": {
+                        "description": "InternalServerError",
                         "schema": {
                             "type": "integer"
                         }
-                    },
-                    "305": {
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "create a new shortlink",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "api/v1/"
+                ],
+                "summary": "create new shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "shortlink spec",
+                        "name": "spec",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "delete shortlink",
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "api/v1/"
+                ],
+                "summary": "delete shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the domain the shortlink is served under, if the name is used under several domains",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/shortlink/{shortlink}/{path}": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "get a shortlink",
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "api/v1/"
+                ],
+                "summary": "get a shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook",
+                        "name": "path",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the domain the shortlink is served under, if the name is used under several domains",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "update a new shortlink",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "api/v1/"
+                ],
+                "summary": "update existing shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook",
+                        "name": "path",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the domain the shortlink is served under, if the name is used under several domains",
+                        "name": "domain",
+                        "in": "query"
+                    },
+                    {
+                        "description": "shortlink spec",
+                        "name": "spec",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "create a new shortlink",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "api/v1/"
+                ],
+                "summary": "create new shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook",
+                        "name": "path",
+                        "in": "path"
+                    },
+                    {
+                        "description": "shortlink spec",
+                        "name": "spec",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI"
+                        }
+                    },
+                    "400": {
+                        "description": "BadRequest",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "delete shortlink",
+                "produces": [
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "api/v1/"
+                ],
+                "summary": "delete shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "home",
+                        "description": "the shortlink URL part (shortlink id)",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook",
+                        "name": "path",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the domain the shortlink is served under, if the name is used under several domains",
+                        "name": "domain",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/{shortlink}": {
+            "get": {
+                "description": "redirect to target as per configuration of the shortlink",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "default"
+                ],
+                "summary": "redirect to target",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "shortlink id",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "show the info page of the shortlink instead of redirecting",
+                        "name": "preview",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "300": {
+                        "description": "MultipleChoices",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "301": {
+                        "description": "MovedPermanently",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "303": {
+                        "description": "SeeOther",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "304": {
+                        "description": "NotModified",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "305": {
+                        "description": "UseProxy",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "307": {
+                        "description": "TemporaryRedirect",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "308": {
+                        "description": "PermanentRedirect",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "503": {
+                        "description": "ServiceUnavailable",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "check the password of a password-protected shortlink and redirect back to it",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "default"
+                ],
+                "summary": "unlock a password-protected shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "shortlink id",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "password of the shortlink",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "SeeOther",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "429": {
+                        "description": "TooManyRequests",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            }
+        },
+        "/{shortlink}/{rest}": {
+            "get": {
+                "description": "redirect to target as per configuration of the shortlink",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "default"
+                ],
+                "summary": "redirect to target",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "shortlink id",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "path passed through to the target",
+                        "name": "rest",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "show the info page of the shortlink instead of redirecting",
+                        "name": "preview",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Success",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "300": {
+                        "description": "MultipleChoices",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "301": {
+                        "description": "MovedPermanently",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "302": {
+                        "description": "Found",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "303": {
+                        "description": "SeeOther",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "304": {
+                        "description": "NotModified",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "305": {
                         "description": "UseProxy",
                         "schema": {
                             "type": "integer"
@@ -401,12 +1188,97 @@ const docTemplate = `{
                             "type": "integer"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "404": {
+                        "description": "NotFound",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "500": {
+                        "description": "InternalServerError",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "503": {
+                        "description": "ServiceUnavailable",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "check the password of a password-protected shortlink and redirect back to it",
+                "consumes": [
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "default"
+                ],
+                "summary": "unlock a password-protected shortlink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "shortlink id",
+                        "name": "shortlink",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "path passed through to the target",
+                        "name": "rest",
+                        "in": "path"
+                    },
+                    {
+                        "type": "string",
+                        "description": "password of the shortlink",
+                        "name": "password",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "303": {
+                        "description": "SeeOther",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "404": {
                         "description": "NotFound",
                         "schema": {
                             "type": "integer"
                         }
                     },
+                    "429": {
+                        "description": "TooManyRequests",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
                     "500": {
                         "description": "InternalServerError",
                         "schema": {
@@ -418,27 +1290,78 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "api.ShortLink": {
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.PassthroughSpec": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "description": "Path appends the path following the shortlink name to the target\n+kubebuilder:default:=false",
+                    "type": "boolean"
+                },
+                "query": {
+                    "description": "Query appends the query string of the request to the target\n+kubebuilder:default:=false",
+                    "type": "boolean"
+                }
+            }
+        },
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.ScheduleEntry": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "description": "At is the point in time from which on this entry is active\n+kubebuilder:validation:Required\n+kubebuilder:validation:Format:date-time",
+                    "type": "string"
+                },
+                "code": {
+                    "description": "Code is the URL Code used for the redirection while this entry is active.\nIf unset, the Code of the Shortlink is used.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Enum=200;300;301;302;303;304;305;307;308",
+                    "type": "integer",
+                    "enum": [
+                        200,
+                        300,
+                        301,
+                        302,
+                        303,
+                        304,
+                        305,
+                        307,
+                        308
+                    ]
+                },
+                "target": {
+                    "description": "Target specifies the target to which we will redirect while this entry is active\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.ShortLinkAPI": {
             "type": "object",
             "properties": {
                 "name": {
                     "type": "string"
                 },
                 "spec": {
-                    "$ref": "#/definitions/v1alpha1.ShortLinkSpec"
+                    "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec"
                 },
                 "status": {
-                    "$ref": "#/definitions/v1alpha1.ShortLinkStatus"
+                    "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkStatus"
+                },
+                "url": {
+                    "type": "string"
                 }
             }
         },
-        "v1alpha1.ShortLinkSpec": {
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkSpec": {
             "type": "object",
             "properties": {
                 "after": {
-                    "description": "RedirectAfter specifies after how many seconds to redirect (Default=3)\n+kubebuilder:default:=0\n+kubebuilder:validation:Minimum=0\n+kubebuilder:validation:Maximum=99",
+                    "description": "RedirectAfter specifies after how many seconds the HTML redirect (Code 200) redirects.\nIf unset, the HTML redirect redirects immediately.\n+kubebuilder:default:=0\n+kubebuilder:validation:Minimum=0\n+kubebuilder:validation:Maximum=99",
                     "type": "integer"
                 },
+                "aliases": {
+                    "description": "Aliases are additional names the shortlink can be called by.\nNames and aliases are matched ignoring case as well as '-' and '_', and must be unique among the shortlinks served under the same domain.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:items:MinLength=1\n+kubebuilder:validation:items:Pattern=` + "`" + `^[^/+]+$` + "`" + `",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "code": {
                     "description": "Code is the URL Code used for the redirection.\nleave on default (307) when using the HTML behavior. However, if you whish to use a HTTP 3xx redirect, set to the appropriate 3xx status code\n+kubebuilder:validation:Enum=200;300;301;302;303;304;305;307;308\n+kubebuilder:default:=307",
                     "type": "integer",
@@ -454,6 +1377,41 @@ const docTemplate = `{
                         308
                     ]
                 },
+                "deleteAfterExpiry": {
+                    "description": "DeleteAfterExpiry is the grace period after which an expired shortlink is deleted.\nIf unset, expired shortlinks are kept.\n+kubebuilder:validation:Optional",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/v1.Duration"
+                        }
+                    ]
+                },
+                "domains": {
+                    "description": "Domains are the hosts the shortlink is served under, such as go.corp or s.brand.com.\nA shortlink without domains is served under every host, unless a shortlink of the same name is bound to the host.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:items:Pattern=` + "`" + `^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$` + "`" + `",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "expiresAt": {
+                    "description": "ExpiresAt is the point in time after which the shortlink is expired and no longer redirects\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Format:date-time",
+                    "type": "string"
+                },
+                "fallbackTarget": {
+                    "description": "FallbackTarget is redirected to instead of the live target, Target or the target of the active schedule entry,\nwhile the last health probe found it unreachable.\nWithout a FallbackTarget, visitors are shown that the target is currently down.\n+kubebuilder:validation:Optional",
+                    "type": "string"
+                },
+                "maxClicks": {
+                    "description": "MaxClicks is the number of invocations after which the shortlink is expired. 0 means unlimited.\nIt is a soft limit: every replica counts its own invocations until they are written to the status,\nso with several replicas the budget may be exceeded by the invocations served in between.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Minimum=0",
+                    "type": "integer"
+                },
+                "noArgsTarget": {
+                    "description": "NoArgsTarget is used instead of a templated Target when the shortlink is called without any arguments\n+kubebuilder:validation:Optional",
+                    "type": "string"
+                },
+                "notBefore": {
+                    "description": "NotBefore is the point in time before which the shortlink is not yet active\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Format:date-time",
+                    "type": "string"
+                },
                 "owner": {
                     "description": "Owner is the GitHub user name which created the shortlink\n+kubebuilder:validation:Required",
                     "type": "string"
@@ -465,35 +1423,298 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "passthrough": {
+                    "description": "Passthrough configures which parts of the request are appended to the target,\ne.g. to redirect go/docs/some/page to \u003ctarget\u003e/some/page\n+kubebuilder:validation:Optional",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.PassthroughSpec"
+                        }
+                    ]
+                },
+                "passwordSecretRef": {
+                    "description": "PasswordSecretRef selects a key of a Secret in the namespace of the Shortlink holding the bcrypt hash\nof a password. If set, visitors have to enter the password before they are redirected.\nThe Secret has to be labelled urlshortener.cedi.dev/password=true.\n+kubebuilder:validation:Optional",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/v1.SecretKeySelector"
+                        }
+                    ]
+                },
+                "path": {
+                    "description": "Path is a hierarchical name of the shortlink, such as sre/runbook, which gives teams their own sub-trees.\nRequests are matched to the shortlink with the longest matching path, and the remaining path is passed on.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Pattern=` + "`" + `^[^/+]+(/[^/+]+)*$` + "`" + `",
+                    "type": "string"
+                },
+                "rules": {
+                    "description": "Rules are evaluated in order for every request. The target of the first matching rule\nis used instead of Target.\n+kubebuilder:validation:Optional",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.TargetRule"
+                    }
+                },
+                "schedule": {
+                    "description": "Schedule lists target changes that take effect at a given point in time.\nThe latest entry whose point in time has passed overrides Target and Code.\n+kubebuilder:validation:Optional",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ScheduleEntry"
+                    }
+                },
+                "slug": {
+                    "description": "Slug is the user-facing name of the shortlink, if it is not a valid Kubernetes object name itself,\nsuch as Team_Docs or an emoji. The object is then named after a sanitized form and a hash of the slug.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Pattern=` + "`" + `^[^/+]+$` + "`" + `",
+                    "type": "string"
+                },
+                "stickyVariants": {
+                    "description": "StickyVariants assigns a visitor the same variant on every visit using a cookie\n+kubebuilder:default:=false",
+                    "type": "boolean"
+                },
                 "target": {
-                    "description": "Target specifies the target to which we will redirect\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1",
+                    "description": "Target specifies the target to which we will redirect.\nThe target may contain placeholders which are filled from the request: {1}, {2}, ... for the\npath segments following the shortlink name, {*} for all of them and {name} for the query parameter \"name\",\ne.g. https://jira.example.com/browse/{1} or https://search.example.com?q={q}\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1",
                     "type": "string"
+                },
+                "variants": {
+                    "description": "Variants split the traffic of the shortlink between several targets by weight.\nIf set, a variant is picked for every request instead of using Target or Schedule.\nMatching Rules still take precedence over Variants.\n+kubebuilder:validation:Optional",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.TargetVariant"
+                    }
+                },
+                "visibility": {
+                    "description": "Visibility defines who may follow the shortlink: anyone (public), every signed-in\nGitHub user (authenticated) or only the owner and co-owners (owners)\n+kubebuilder:default:=public",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkVisibility"
+                        }
+                    ]
                 }
             }
         },
-        "v1alpha1.ShortLinkStatus": {
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkStatus": {
             "type": "object",
             "properties": {
                 "changedby": {
                     "description": "ChangedBy indicates who (GitHub User) changed the Shortlink last\n+kubebuilder:validation:Optional",
                     "type": "string"
                 },
+                "conditions": {
+                    "description": "Conditions represent the latest available observations of the Shortlink's state\n+listType=map\n+listMapKey=type\n+kubebuilder:validation:Optional",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/v1.Condition"
+                    }
+                },
                 "count": {
                     "description": "Count represents how often this ShortLink has been called\n+kubebuilder:default:=0\n+kubebuilder:validation:Minimum=0",
                     "type": "integer"
                 },
+                "currentTarget": {
+                    "description": "CurrentTarget is the target the Shortlink currently redirects to\n+kubebuilder:validation:Optional",
+                    "type": "string"
+                },
                 "lastmodified": {
                     "description": "LastModified is a date-time when the ShortLink was last modified\n+kubebuilder:validation:Format:date-time\n+kubebuilder:validation:Optional",
                     "type": "string"
+                },
+                "nextTarget": {
+                    "description": "NextTarget is the target the Shortlink redirects to after the next scheduled change\n+kubebuilder:validation:Optional",
+                    "type": "string"
+                },
+                "nextTransition": {
+                    "description": "NextTransition is the point in time of the next scheduled change\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Format:date-time",
+                    "type": "string"
+                },
+                "targetCheckedAt": {
+                    "description": "TargetCheckedAt is the point in time of the last health probe\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Format:date-time",
+                    "type": "string"
+                },
+                "targetStatusCode": {
+                    "description": "TargetStatusCode is the HTTP status code the target answered the last health probe with\n+kubebuilder:validation:Optional",
+                    "type": "integer"
+                },
+                "variantCounts": {
+                    "description": "VariantCounts represents how often each variant of the ShortLink has been called\n+kubebuilder:validation:Optional",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.ShortlinkVisibility": {
+            "type": "string",
+            "enum": [
+                "public",
+                "authenticated",
+                "owners"
+            ],
+            "x-enum-varnames": [
+                "ShortlinkVisibilityPublic",
+                "ShortlinkVisibilityAuthenticated",
+                "ShortlinkVisibilityOwners"
+            ]
+        },
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.TargetRule": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is the URL Code used for the redirection if the expression matches.\nIf unset, the Code of the Shortlink is used.\n+kubebuilder:validation:Optional\n+kubebuilder:validation:Enum=200;300;301;302;303;304;305;307;308",
+                    "type": "integer",
+                    "enum": [
+                        200,
+                        300,
+                        301,
+                        302,
+                        303,
+                        304,
+                        305,
+                        307,
+                        308
+                    ]
+                },
+                "expression": {
+                    "description": "Expression is a CEL expression that is evaluated against the request and must return a bool.\nIt can use the variables headers, language, userAgent, ip, path, query and now,\ne.g. language == 'de' or cidr('10.0.0.0/8').containsIP(ip)\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1",
+                    "type": "string"
+                },
+                "target": {
+                    "description": "Target specifies the target to which we will redirect if the expression matches\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_spechtlabs_urlshortener_api_v1alpha1.TargetVariant": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Name identifies the variant in the status and metrics of the Shortlink\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1\n+kubebuilder:validation:MaxLength=63\n+kubebuilder:validation:Pattern=` + "`" + `^[A-Za-z0-9_-]+$` + "`" + `",
+                    "type": "string"
+                },
+                "target": {
+                    "description": "Target specifies the target to which we will redirect if this variant is picked\n+kubebuilder:validation:Required\n+kubebuilder:validation:MinLength=1",
+                    "type": "string"
+                },
+                "weight": {
+                    "description": "Weight is the relative share of the traffic this variant receives\n+kubebuilder:default:=1\n+kubebuilder:validation:Minimum=0",
+                    "type": "integer"
+                }
+            }
+        },
+        "k8s_io_apimachinery_pkg_apis_meta_v1.ConditionStatus": {
+            "type": "string",
+            "enum": [
+                "True",
+                "False",
+                "Unknown"
+            ],
+            "x-enum-varnames": [
+                "ConditionTrue",
+                "ConditionFalse",
+                "ConditionUnknown"
+            ]
+        },
+        "v1.Condition": {
+            "type": "object",
+            "properties": {
+                "lastTransitionTime": {
+                    "description": "lastTransitionTime is the last time the condition transitioned from one status to another.\nThis should be when the underlying condition changed.  If that is not known, then using the time when the API field changed is acceptable.\n+required\n+kubebuilder:validation:Required\n+kubebuilder:validation:Type=string\n+kubebuilder:validation:Format=date-time",
+                    "type": "string"
+                },
+                "message": {
+                    "description": "message is a human readable message indicating details about the transition.\nThis may be an empty string.\n+required\n+kubebuilder:validation:Required\n+kubebuilder:validation:MaxLength=32768",
+                    "type": "string"
+                },
+                "observedGeneration": {
+                    "description": "observedGeneration represents the .metadata.generation that the condition was set based upon.\nFor instance, if .metadata.generation is currently 12, but the .status.conditions[x].observedGeneration is 9, the condition is out of date\nwith respect to the current state of the instance.\n+optional\n+kubebuilder:validation:Minimum=0",
+                    "type": "integer"
+                },
+                "reason": {
+                    "description": "reason contains a programmatic identifier indicating the reason for the condition's last transition.\nProducers of specific condition types may define expected values and meanings for this field,\nand whether the values are considered a guaranteed API.\nThe value should be a CamelCase string.\nThis field may not be empty.\n+required\n+kubebuilder:validation:Required\n+kubebuilder:validation:MaxLength=1024\n+kubebuilder:validation:MinLength=1\n+kubebuilder:validation:Pattern=` + "`" + `^[A-Za-z]([A-Za-z0-9_,:]*[A-Za-z0-9_])?$` + "`" + `",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "status of the condition, one of True, False, Unknown.\n+required\n+kubebuilder:validation:Required\n+kubebuilder:validation:Enum=True;False;Unknown",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/k8s_io_apimachinery_pkg_apis_meta_v1.ConditionStatus"
+                        }
+                    ]
+                },
+                "type": {
+                    "description": "type of condition in CamelCase or in foo.example.com/CamelCase.\n---\nMany .condition.type values are consistent across resources like Available, but because arbitrary conditions can be\nuseful (see .node.status.conditions), the ability to deconflict is important.\nThe regex it matches is (dns1123SubdomainFmt/)?(qualifiedNameFmt)\n+required\n+kubebuilder:validation:Required\n+kubebuilder:validation:Pattern=` + "`" + `^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$` + "`" + `\n+kubebuilder:validation:MaxLength=316",
+                    "type": "string"
+                }
+            }
+        },
+        "v1.Duration": {
+            "type": "object",
+            "properties": {
+                "time.Duration": {
+                    "type": "integer",
+                    "enum": [
+                        -9223372036854775808,
+                        9223372036854775807,
+                        1,
+                        1000,
+                        1000000,
+                        1000000000,
+                        60000000000,
+                        3600000000000,
+                        -9223372036854775808,
+                        9223372036854775807,
+                        1,
+                        1000,
+                        1000000,
+                        1000000000,
+                        60000000000,
+                        3600000000000
+                    ],
+                    "x-enum-varnames": [
+                        "minDuration",
+                        "maxDuration",
+                        "Nanosecond",
+                        "Microsecond",
+                        "Millisecond",
+                        "Second",
+                        "Minute",
+                        "Hour",
+                        "minDuration",
+                        "maxDuration",
+                        "Nanosecond",
+                        "Microsecond",
+                        "Millisecond",
+                        "Second",
+                        "Minute",
+                        "Hour"
+                    ]
+                }
+            }
+        },
+        "v1.SecretKeySelector": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "The key of the secret to select from.  Must be a valid secret key.",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Name of the referent.\nThis field is effectively required, but due to backwards compatibility is\nallowed to be empty. Instances of this type with an empty value here are\nalmost certainly wrong.\nMore info: https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#names\n+optional\n+default=\"\"\n+kubebuilder:default=\"\"\nTODO: Drop ` + "`" + `kubebuilder:default` + "`" + ` when controller-gen doesn't need it https://github.com/kubernetes-sigs/kubebuilder/issues/3896.",
+                    "type": "string"
+                },
+                "optional": {
+                    "description": "Specify whether the Secret or its key must be defined\n+optional",
+                    "type": "boolean"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "bearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "1.0",
+	Version:          "2.0",
 	Host:             "",
 	BasePath:         "/",
 	Schemes:          []string{},
@@ -501,6 +1722,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "A url shortener, written in Go running on Kubernetes",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
@@ -38,8 +38,52 @@ var shortlinkInvocations = prometheus.NewGaugeVec(
 	},
 )
 
+var shortlinkVariantInvocations = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "urlshortener_shortlink_variant_invocation",
+		Help: "Counts of how often each variant of a shortlink was invoked",
+	},
+	[]string{
+		"name",
+		"namespace",
+		"variant",
+	},
+)
+
+var shortlinkBrokenTargets = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "urlshortener_shortlink_target_broken",
+		Help: "Whether the target of a shortlink failed its last health probe (1) or not (0)",
+	},
+	[]string{
+		"name",
+		"namespace",
+	},
+)
+
+var resolverLookups = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "urlshortener_resolver_lookups",
+		Help: "Counts of shortlink lookups served by the resolver cache, partitioned by hit or miss",
+	},
+	[]string{
+		"result",
+	},
+)
+
+var resolverCachedShortlinks = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "urlshortener_resolver_cached_shortlinks",
+		Help: "Number of shortlinks currently held in the resolver cache",
+	},
+)
+
 func init() {
 	metrics.Registry.MustRegister(reconcilerDuration)
 	metrics.Registry.MustRegister(active)
 	metrics.Registry.MustRegister(shortlinkInvocations)
+	metrics.Registry.MustRegister(shortlinkVariantInvocations)
+	metrics.Registry.MustRegister(shortlinkBrokenTargets)
+	metrics.Registry.MustRegister(resolverLookups)
+	metrics.Registry.MustRegister(resolverCachedShortlinks)
 }
@@ -18,6 +18,7 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -25,12 +26,18 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/pkg/errors"
 	"github.com/spechtlabs/go-otel-utils/otelzap"
@@ -39,28 +46,50 @@ import (
 	rClient "github.com/spechtlabs/urlshortener/pkg/client"
 )
 
+// tlsSecretRecheckInterval is how long to wait before checking again for a TLS Secret that does not exist yet,
+// e.g. because cert-manager is still issuing the certificate
+const tlsSecretRecheckInterval = 30 * time.Second
+
 // RedirectReconciler reconciles a Redirect object
 type RedirectReconciler struct {
-	client  client.Client
-	rClient *rClient.RedirectClient
+	client    client.Client
+	apiReader client.Reader
+	rClient   *rClient.RedirectClient
+	recorder  record.EventRecorder
+
+	// defaultBackend serves the Redirects that do not select a backend themselves
+	defaultBackend v1alpha1.RedirectBackend
 
 	scheme *runtime.Scheme
 	tracer trace.Tracer
 }
 
-// NewRedirectReconciler returns a new RedirectReconciler
-func NewRedirectReconciler(client client.Client, scheme *runtime.Scheme) *RedirectReconciler {
+// NewRedirectReconciler returns a new RedirectReconciler. The apiReader reads the TLS Secrets of the Redirects
+// directly from the API server, so the manager does not need to cache all Secrets of the cluster.
+// Redirects that do not select a backend are served by the defaultBackend, or by an Ingress if it is empty.
+func NewRedirectReconciler(client client.Client, apiReader client.Reader, scheme *runtime.Scheme, recorder record.EventRecorder, defaultBackend v1alpha1.RedirectBackend) *RedirectReconciler {
+	if defaultBackend == "" {
+		defaultBackend = v1alpha1.RedirectBackendIngress
+	}
+
 	return &RedirectReconciler{
-		client:  client,
-		rClient: rClient.NewRedirectClient(client),
-		scheme:  scheme,
-		tracer:  otel.Tracer("urlshortener"),
+		client:         client,
+		apiReader:      apiReader,
+		rClient:        rClient.NewRedirectClient(client),
+		recorder:       recorder,
+		defaultBackend: defaultBackend,
+		scheme:         scheme,
+		tracer:         otel.Tracer("urlshortener"),
 	}
 }
 
 // +kubebuilder:rbac:groups=urlshortener.cedi.dev,resources=redirects,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=urlshortener.cedi.dev,resources=redirects/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=urlshortener.cedi.dev,resources=redirects/finalizers,verbs=update
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -113,16 +142,39 @@ func (r *RedirectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
-	// Check if the ingress already exists, if not create a new one
-	ingress, err := r.upsertRedirectIngress(ctx, redirect)
-	if err != nil {
-		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to upsert redirect ingress",
+	// Record the outcome of every step in the conditions, even if syncing the ingress or HTTPRoute failed
+	backend := r.backend(redirect)
+
+	var syncErr error
+	syncedCondition := v1alpha1.RedirectConditionIngressSynced
+	if backend == v1alpha1.RedirectBackendHTTPRoute {
+		syncedCondition = v1alpha1.RedirectConditionHTTPRouteSynced
+		syncErr = r.syncHTTPRoute(ctx, redirect)
+		meta.RemoveStatusCondition(&redirect.Status.Conditions, v1alpha1.RedirectConditionIngressSynced)
+	} else {
+		syncErr = r.syncIngress(ctx, redirect)
+		meta.RemoveStatusCondition(&redirect.Status.Conditions, v1alpha1.RedirectConditionHTTPRouteSynced)
+	}
+
+	tlsReady, tlsErr := r.updateTLSCondition(ctx, redirect, backend)
+	updateRedirectReadyCondition(redirect, backend, syncedCondition)
+
+	if syncErr != nil {
+		otelzap.L().WithError(syncErr).Ctx(ctx).Error("Failed to sync redirect backend",
 			zap.String("name", "reconciler"),
 			zap.String("redirect", req.String()),
+			zap.String("backend", string(backend)),
 		)
 	}
 
-	// Update the Redirect status with the ingress name and the target
+	if tlsErr != nil {
+		otelzap.L().WithError(tlsErr).Ctx(ctx).Error("Failed to check redirect TLS secret",
+			zap.String("name", "reconciler"),
+			zap.String("redirect", req.String()),
+		)
+	}
+
+	// Update the Redirect status with the ingress and HTTPRoute names and the target
 	ingressList := &networkingv1.IngressList{}
 	listOpts := []client.ListOption{
 		client.InNamespace(redirect.Namespace),
@@ -137,11 +189,26 @@ func (r *RedirectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
-	// Update status.Nodes if needed
-	redirect.Status.IngressName = GetIngressNames(ingressList.Items)
-	redirect.Status.Target = ingress.Annotations["nginx.ingress.kubernetes.io/permanent-redirect"]
-	err = r.client.Status().Update(ctx, redirect)
+	routeList, err := r.listHTTPRoutes(ctx, redirect)
 	if err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to list HTTPRoutes",
+			zap.String("name", "reconciler"),
+			zap.String("redirect", req.String()),
+		)
+		return ctrl.Result{}, err
+	}
+
+	redirect.Status.IngressName = GetIngressNames(ingressList.Items)
+	redirect.Status.HTTPRouteNames = GetHTTPRouteNames(routeList.Items)
+	if syncErr == nil {
+		// HTTPRoutes keep the path of the request without the nginx $request_uri variable
+		redirect.Status.Target = normalizeUrl(redirect.Spec.Target)
+		if backend == v1alpha1.RedirectBackendHTTPRoute {
+			redirect.Status.Target = strings.TrimSuffix(redirect.Status.Target, "$request_uri")
+		}
+	}
+
+	if err = r.client.Status().Update(ctx, redirect); err != nil {
 		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to update Redirect status",
 			zap.String("name", "reconciler"),
 			zap.String("redirect", req.String()),
@@ -149,33 +216,328 @@ func (r *RedirectReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	// Returning the error requeues the Redirect with exponential backoff
+	if syncErr != nil {
+		return ctrl.Result{}, syncErr
+	}
+
+	if tlsErr != nil {
+		return ctrl.Result{}, tlsErr
+	}
+
+	if !tlsReady {
+		return ctrl.Result{RequeueAfter: tlsSecretRecheckInterval}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
-func (r *RedirectReconciler) upsertRedirectIngress(ctx context.Context, redirect *v1alpha1.Redirect) (*networkingv1.Ingress, error) {
-	ingress := &networkingv1.Ingress{}
-	err := r.client.Get(ctx, types.NamespacedName{Name: redirect.Name, Namespace: redirect.Namespace}, ingress)
-	ingress = UpdateRedirectIngress(ingress, redirect, r.scheme)
+// backend returns the backend serving the redirect
+func (r *RedirectReconciler) backend(redirect *v1alpha1.Redirect) v1alpha1.RedirectBackend {
+	if redirect.Spec.Backend != "" {
+		return redirect.Spec.Backend
+	}
+
+	return r.defaultBackend
+}
+
+// syncIngress creates the ingress of the redirect, or updates it if it differs from the redirect, deletes stale
+// ingresses and HTTPRoutes of the redirect and records the outcome in the IngressSynced condition and in Events
+func (r *RedirectReconciler) syncIngress(ctx context.Context, redirect *v1alpha1.Redirect) error {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      redirect.Name,
+			Namespace: redirect.Namespace,
+		},
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.client, ingress, func() error {
+		_, err := UpdateRedirectIngress(ingress, redirect, r.scheme)
+		return err
+	})
+
+	switch result {
+	case controllerutil.OperationResultCreated:
+		r.recorder.Eventf(redirect, corev1.EventTypeNormal, v1alpha1.RedirectReasonIngressCreated, "Created Ingress %s", ingress.Name)
+	case controllerutil.OperationResultUpdated:
+		r.recorder.Eventf(redirect, corev1.EventTypeNormal, v1alpha1.RedirectReasonIngressUpdated, "Updated Ingress %s", ingress.Name)
+	}
+
+	if err == nil {
+		err = r.deleteStaleIngresses(ctx, redirect, ingress.Name)
+	}
+
+	// HTTPRoutes are left behind if the redirect was served by the HTTPRoute backend before
+	if err == nil {
+		err = r.deleteStaleHTTPRoutes(ctx, redirect, "")
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.RedirectConditionIngressSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.RedirectReasonIngressSynced,
+		Message:            "Ingress " + ingress.Name + " matches the Redirect",
+		ObservedGeneration: redirect.Generation,
+	}
+
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.RedirectReasonIngressSyncFailed
+		condition.Message = err.Error()
+		meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+
+		r.recorder.Eventf(redirect, corev1.EventTypeWarning, v1alpha1.RedirectReasonIngressSyncFailed, "Failed to sync Ingress %s: %v", ingress.Name, err)
+		return errors.Wrap(err, "Failed to sync redirect Ingress")
+	}
+
+	meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+	return nil
+}
+
+// syncHTTPRoute creates the HTTPRoute of the redirect, or updates it if it differs from the redirect, deletes stale
+// HTTPRoutes and ingresses of the redirect and records the outcome in the HTTPRouteSynced condition and in Events
+func (r *RedirectReconciler) syncHTTPRoute(ctx context.Context, redirect *v1alpha1.Redirect) error {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+	route.SetName(redirect.Name)
+	route.SetNamespace(redirect.Namespace)
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.client, route, func() error {
+		_, err := UpdateRedirectHTTPRoute(route, redirect, r.scheme)
+		return err
+	})
+
+	switch result {
+	case controllerutil.OperationResultCreated:
+		r.recorder.Eventf(redirect, corev1.EventTypeNormal, v1alpha1.RedirectReasonHTTPRouteCreated, "Created HTTPRoute %s", route.GetName())
+	case controllerutil.OperationResultUpdated:
+		r.recorder.Eventf(redirect, corev1.EventTypeNormal, v1alpha1.RedirectReasonHTTPRouteUpdated, "Updated HTTPRoute %s", route.GetName())
+	}
+
+	if err == nil {
+		err = r.deleteStaleHTTPRoutes(ctx, redirect, route.GetName())
+	}
+
+	// Ingresses are left behind if the redirect was served by the Ingress backend before
+	if err == nil {
+		err = r.deleteStaleIngresses(ctx, redirect, "")
+	}
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.RedirectConditionHTTPRouteSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.RedirectReasonHTTPRouteSynced,
+		Message:            "HTTPRoute " + route.GetName() + " matches the Redirect",
+		ObservedGeneration: redirect.Generation,
+	}
+
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.RedirectReasonHTTPRouteSyncFailed
+		condition.Message = err.Error()
+		meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+
+		r.recorder.Eventf(redirect, corev1.EventTypeWarning, v1alpha1.RedirectReasonHTTPRouteSyncFailed, "Failed to sync HTTPRoute %s: %v", route.GetName(), err)
+		return errors.Wrap(err, "Failed to sync redirect HTTPRoute")
+	}
+
+	meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+	return nil
+}
+
+// deleteStaleIngresses deletes the ingresses the redirect controls besides the one it currently needs,
+// such as ingresses left behind by an earlier source of the redirect
+func (r *RedirectReconciler) deleteStaleIngresses(ctx context.Context, redirect *v1alpha1.Redirect, keep string) error {
+	ingressList := &networkingv1.IngressList{}
+	listOpts := []client.ListOption{
+		client.InNamespace(redirect.Namespace),
+		client.MatchingLabels(GetLabelsForRedirect(redirect.Name)),
+	}
+
+	if err := r.client.List(ctx, ingressList, listOpts...); err != nil {
+		return errors.Wrap(err, "Failed to list redirect Ingresses")
+	}
+
+	for idx := range ingressList.Items {
+		ingress := &ingressList.Items[idx]
+		if ingress.Name == keep || !metav1.IsControlledBy(ingress, redirect) {
+			continue
+		}
+
+		if err := r.client.Delete(ctx, ingress); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "Failed to delete stale Ingress %s", ingress.Name)
+		}
+
+		r.recorder.Eventf(redirect, corev1.EventTypeNormal, v1alpha1.RedirectReasonIngressDeleted, "Deleted stale Ingress %s", ingress.Name)
+	}
+
+	return nil
+}
+
+// deleteStaleHTTPRoutes deletes the HTTPRoutes the redirect controls besides the one it currently needs
+func (r *RedirectReconciler) deleteStaleHTTPRoutes(ctx context.Context, redirect *v1alpha1.Redirect, keep string) error {
+	routeList, err := r.listHTTPRoutes(ctx, redirect)
+	if err != nil {
+		return err
+	}
 
-	if err != nil && k8serrors.IsNotFound(err) {
-		if err := r.client.Create(ctx, ingress); err != nil {
-			return nil, errors.Wrap(err, "Failed to create new Ingress")
+	for idx := range routeList.Items {
+		route := &routeList.Items[idx]
+		if route.GetName() == keep || !metav1.IsControlledBy(route, redirect) {
+			continue
 		}
-	} else if err != nil {
-		return nil, errors.Wrap(err, "Failed to get redirect Ingress")
+
+		if err := r.client.Delete(ctx, route); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrapf(err, "Failed to delete stale HTTPRoute %s", route.GetName())
+		}
+
+		r.recorder.Eventf(redirect, corev1.EventTypeNormal, v1alpha1.RedirectReasonHTTPRouteDeleted, "Deleted stale HTTPRoute %s", route.GetName())
 	}
 
-	if err := r.client.Update(ctx, ingress); err != nil {
-		return nil, errors.Wrap(err, "Failed to update redirect Ingress")
+	return nil
+}
+
+// listHTTPRoutes returns the HTTPRoutes labeled for the redirect.
+// The list is empty if the Gateway API is not installed in the cluster.
+func (r *RedirectReconciler) listHTTPRoutes(ctx context.Context, redirect *v1alpha1.Redirect) (*unstructured.UnstructuredList, error) {
+	routeList := &unstructured.UnstructuredList{}
+	routeList.SetGroupVersionKind(HTTPRouteListGroupVersionKind)
+
+	listOpts := []client.ListOption{
+		client.InNamespace(redirect.Namespace),
+		client.MatchingLabels(GetLabelsForRedirect(redirect.Name)),
+	}
+
+	if err := r.client.List(ctx, routeList, listOpts...); err != nil {
+		if meta.IsNoMatchError(err) {
+			return routeList, nil
+		}
+
+		return nil, errors.Wrap(err, "Failed to list redirect HTTPRoutes")
 	}
 
-	return ingress, nil
+	return routeList, nil
+}
+
+// updateTLSCondition checks the Secret holding the TLS certificate of the redirect, records the outcome in the
+// TLSReady condition and returns true if the certificate is available or TLS is not enabled.
+// HTTPRoutes do not terminate TLS themselves, so the certificate is not checked for the HTTPRoute backend.
+func (r *RedirectReconciler) updateTLSCondition(ctx context.Context, redirect *v1alpha1.Redirect, backend v1alpha1.RedirectBackend) (bool, error) {
+	condition := metav1.Condition{
+		Type:               v1alpha1.RedirectConditionTLSReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.RedirectReasonTLSDisabled,
+		Message:            "TLS is not enabled",
+		ObservedGeneration: redirect.Generation,
+	}
+
+	if backend == v1alpha1.RedirectBackendHTTPRoute {
+		condition.Reason = v1alpha1.RedirectReasonTLSManagedByGateway
+		condition.Message = "TLS is terminated by the Gateways the HTTPRoute attaches to"
+		meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+		return true, nil
+	}
+
+	if !redirect.Spec.TLS.Enable {
+		meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+		return true, nil
+	}
+
+	secretName := GetTLSSecretName(redirect)
+	secret := &corev1.Secret{}
+	err := r.apiReader.Get(ctx, types.NamespacedName{Name: secretName, Namespace: redirect.Namespace}, secret)
+
+	switch {
+	case err != nil && !k8serrors.IsNotFound(err):
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = v1alpha1.RedirectReasonSecretMissing
+		condition.Message = err.Error()
+		meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+		return false, errors.Wrapf(err, "Failed to get TLS Secret %s", secretName)
+
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.RedirectReasonSecretMissing
+		condition.Message = "Secret " + secretName + " does not exist yet"
+
+	case len(secret.Data[corev1.TLSCertKey]) == 0 || len(secret.Data[corev1.TLSPrivateKeyKey]) == 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.RedirectReasonSecretMissing
+		condition.Message = "Secret " + secretName + " has no " + corev1.TLSCertKey + " or " + corev1.TLSPrivateKeyKey
+
+	default:
+		condition.Reason = v1alpha1.RedirectReasonSecretAvailable
+		condition.Message = "Secret " + secretName + " holds the TLS certificate"
+	}
+
+	// Only report a missing certificate once, not on every recheck
+	if meta.SetStatusCondition(&redirect.Status.Conditions, condition) && condition.Status == metav1.ConditionFalse {
+		r.recorder.Event(redirect, corev1.EventTypeWarning, v1alpha1.RedirectReasonSecretMissing, condition.Message)
+	}
+
+	return condition.Status == metav1.ConditionTrue, nil
+}
+
+// updateRedirectReadyCondition sets the Ready condition of the redirect from the condition reporting whether its
+// ingress or HTTPRoute is synced, and from its TLSReady condition. An HTTPRoute without Gateway is never ready.
+func updateRedirectReadyCondition(redirect *v1alpha1.Redirect, backend v1alpha1.RedirectBackend, syncedCondition string) {
+	condition := metav1.Condition{
+		Type:               v1alpha1.RedirectConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.RedirectReasonReady,
+		Message:            "Redirect is ready",
+		ObservedGeneration: redirect.Generation,
+	}
+
+	for _, conditionType := range []string{syncedCondition, v1alpha1.RedirectConditionTLSReady} {
+		dependency := meta.FindStatusCondition(redirect.Status.Conditions, conditionType)
+		if dependency == nil || dependency.Status != metav1.ConditionTrue {
+			condition.Status = metav1.ConditionFalse
+			if dependency != nil {
+				condition.Reason = dependency.Reason
+				condition.Message = dependency.Message
+			}
+			break
+		}
+	}
+
+	if condition.Status == metav1.ConditionTrue && backend == v1alpha1.RedirectBackendHTTPRoute && len(redirect.Spec.ParentRefs) == 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.RedirectReasonNoGateway
+		condition.Message = "The HTTPRoute attaches to no Gateway, set parentRefs to serve the Redirect"
+	}
+
+	meta.SetStatusCondition(&redirect.Status.Conditions, condition)
+}
+
+// ownedObjects returns the kinds of objects the controller creates for redirects.
+// Changes to these objects trigger a reconcile of their redirect, which reverts any drift.
+func (r *RedirectReconciler) ownedObjects(mapper meta.RESTMapper) []client.Object {
+	objects := []client.Object{&networkingv1.Ingress{}}
+
+	// HTTPRoutes can only be watched if the Gateway API is installed in the cluster
+	if _, err := mapper.RESTMapping(HTTPRouteGroupVersionKind.GroupKind(), HTTPRouteGroupVersionKind.Version); err != nil {
+		otelzap.L().WithError(err).Info("Gateway API HTTPRoutes are not available, not watching them",
+			zap.String("name", "reconciler"),
+		)
+		return objects
+	}
+
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+	return append(objects, route)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RedirectReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1alpha1.Redirect{}).
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Redirect{})
+
+	for _, object := range r.ownedObjects(mgr.GetRESTMapper()) {
+		builder = builder.Owns(object)
+	}
+
+	return builder.
 		Named("redirect").
 		Complete(r)
 }
@@ -21,8 +21,13 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -51,7 +56,12 @@ var _ = Describe("Redirect Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: urlshortenerv1alpha1.RedirectSpec{
+						Source:           "redirect.example.com",
+						Target:           "https://example.com",
+						Code:             308,
+						IngressClassName: "nginx",
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -65,17 +75,189 @@ var _ = Describe("Redirect Controller", func() {
 
 			By("Cleanup the specific resource instance Redirect")
 			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			// envtest runs no garbage collector, so the owned ingress has to be removed as well
+			ingress := &networkingv1.Ingress{}
+			if err := k8sClient.Get(ctx, typeNamespacedName, ingress); err == nil {
+				Expect(k8sClient.Delete(ctx, ingress)).To(Succeed())
+			}
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
-			controllerReconciler := NewRedirectReconciler(k8sClient, k8sClient.Scheme())
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := NewRedirectReconciler(k8sClient, k8sClient, k8sClient.Scheme(), recorder, urlshortenerv1alpha1.RedirectBackendIngress)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the ingress was created and reported")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &networkingv1.Ingress{})).To(Succeed())
+			Expect(recorder.Events).To(Receive(ContainSubstring(urlshortenerv1alpha1.RedirectReasonIngressCreated)))
+
+			resource := &urlshortenerv1alpha1.Redirect{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionIngressSynced)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionReady)).To(BeTrue())
+
+			ready := meta.FindStatusCondition(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionReady)
+			Expect(ready.ObservedGeneration).To(Equal(resource.Generation))
+		})
+
+		It("should not be ready until the TLS secret exists", func() {
+			By("Enabling TLS")
+			resource := &urlshortenerv1alpha1.Redirect{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.TLS.Enable = true
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := NewRedirectReconciler(k8sClient, k8sClient, k8sClient.Scheme(), recorder, urlshortenerv1alpha1.RedirectBackendIngress)
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(tlsSecretRecheckInterval))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			tlsReady := meta.FindStatusCondition(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionTLSReady)
+			Expect(tlsReady).NotTo(BeNil())
+			Expect(tlsReady.Status).To(Equal(metav1.ConditionFalse))
+			Expect(tlsReady.Reason).To(Equal(urlshortenerv1alpha1.RedirectReasonSecretMissing))
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionReady)).To(BeTrue())
+		})
+
+		It("should revert changes to the ingress and delete stale ingresses", func() {
+			controllerReconciler := NewRedirectReconciler(k8sClient, k8sClient, k8sClient.Scheme(), record.NewFakeRecorder(10), urlshortenerv1alpha1.RedirectBackendIngress)
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
-			// Example: If you expect a certain status condition after reconciliation, verify it here.
+
+			By("Changing the redirect target of the ingress")
+			ingress := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ingress)).To(Succeed())
+			ingress.Annotations["nginx.ingress.kubernetes.io/permanent-redirect"] = "https://example.org"
+			Expect(k8sClient.Update(ctx, ingress)).To(Succeed())
+
+			By("Leaving an ingress of an earlier source behind")
+			resource := &urlshortenerv1alpha1.Redirect{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			stale := ingress.DeepCopy()
+			stale.ObjectMeta = metav1.ObjectMeta{
+				Name:      resourceName + "-stale",
+				Namespace: typeNamespacedName.Namespace,
+				Labels:    GetLabelsForRedirect(resourceName),
+			}
+			Expect(controllerutil.SetControllerReference(resource, stale, k8sClient.Scheme())).To(Succeed())
+			Expect(k8sClient.Create(ctx, stale)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler = NewRedirectReconciler(k8sClient, k8sClient, k8sClient.Scheme(), recorder, urlshortenerv1alpha1.RedirectBackendIngress)
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ingress)).To(Succeed())
+			Expect(ingress.Annotations).To(HaveKeyWithValue("nginx.ingress.kubernetes.io/permanent-redirect", "https://example.com"))
+			Expect(recorder.Events).To(Receive(ContainSubstring(urlshortenerv1alpha1.RedirectReasonIngressUpdated)))
+
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: stale.Name, Namespace: stale.Namespace}, &networkingv1.Ingress{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(recorder.Events).To(Receive(ContainSubstring(urlshortenerv1alpha1.RedirectReasonIngressDeleted)))
+		})
+
+		It("should report a failed HTTPRoute sync if the Gateway API is not installed", func() {
+			resource := &urlshortenerv1alpha1.Redirect{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.ParentRefs = []urlshortenerv1alpha1.ParentReference{{Name: "public"}}
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := NewRedirectReconciler(k8sClient, k8sClient, k8sClient.Scheme(), recorder, urlshortenerv1alpha1.RedirectBackendHTTPRoute)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(recorder.Events).To(Receive(ContainSubstring(urlshortenerv1alpha1.RedirectReasonHTTPRouteSyncFailed)))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionHTTPRouteSynced)).To(BeTrue())
+			Expect(meta.FindStatusCondition(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionIngressSynced)).To(BeNil())
+			Expect(meta.IsStatusConditionFalse(resource.Status.Conditions, urlshortenerv1alpha1.RedirectConditionReady)).To(BeTrue())
+		})
+	})
+
+	Context("When rendering an HTTPRoute", func() {
+		redirect := &urlshortenerv1alpha1.Redirect{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "docs",
+				Namespace: "default",
+			},
+			Spec: urlshortenerv1alpha1.RedirectSpec{
+				Source:     "go.example.com",
+				Target:     "https://example.com/docs",
+				Code:       301,
+				Backend:    urlshortenerv1alpha1.RedirectBackendHTTPRoute,
+				ParentRefs: []urlshortenerv1alpha1.ParentReference{{Name: "public", Namespace: "gateways"}},
+			},
+		}
+
+		It("should redirect the source to the target with a RequestRedirect filter", func() {
+			route, err := UpdateRedirectHTTPRoute(nil, redirect, k8sClient.Scheme())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(route.GroupVersionKind()).To(Equal(HTTPRouteGroupVersionKind))
+
+			hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+			Expect(hostnames).To(ConsistOf("go.example.com"))
+
+			parentRefs, _, _ := unstructured.NestedSlice(route.Object, "spec", "parentRefs")
+			Expect(parentRefs).To(ConsistOf(HaveKeyWithValue("kind", "Gateway")))
+
+			rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+			Expect(rules).To(HaveLen(1))
+
+			filters, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "filters")
+			requestRedirect, _, _ := unstructured.NestedMap(filters[0].(map[string]interface{}), "requestRedirect")
+			Expect(requestRedirect).To(HaveKeyWithValue("hostname", "example.com"))
+			Expect(requestRedirect).To(HaveKeyWithValue("scheme", "https"))
+			Expect(requestRedirect).To(HaveKeyWithValue("statusCode", int64(301)))
+		})
+
+		It("should not report an HTTPRoute without Gateway as ready", func() {
+			detached := redirect.DeepCopy()
+			detached.Spec.ParentRefs = nil
+			meta.SetStatusCondition(&detached.Status.Conditions, metav1.Condition{Type: urlshortenerv1alpha1.RedirectConditionHTTPRouteSynced, Status: metav1.ConditionTrue, Reason: urlshortenerv1alpha1.RedirectReasonHTTPRouteSynced})
+			meta.SetStatusCondition(&detached.Status.Conditions, metav1.Condition{Type: urlshortenerv1alpha1.RedirectConditionTLSReady, Status: metav1.ConditionTrue, Reason: urlshortenerv1alpha1.RedirectReasonTLSManagedByGateway})
+
+			updateRedirectReadyCondition(detached, urlshortenerv1alpha1.RedirectBackendHTTPRoute, urlshortenerv1alpha1.RedirectConditionHTTPRouteSynced)
+
+			ready := meta.FindStatusCondition(detached.Status.Conditions, urlshortenerv1alpha1.RedirectConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(urlshortenerv1alpha1.RedirectReasonNoGateway))
+		})
+
+		It("should reject codes the RequestRedirect filter does not support", func() {
+			temporary := redirect.DeepCopy()
+			temporary.Spec.Code = 307
+
+			_, err := UpdateRedirectHTTPRoute(nil, temporary, k8sClient.Scheme())
+			Expect(err).To(MatchError(ContainSubstring("only support the codes 301 and 302")))
+		})
+
+		It("should reject targets with a query", func() {
+			withQuery := redirect.DeepCopy()
+			withQuery.Spec.Target = "https://example.com/docs?lang=en"
+
+			_, err := UpdateRedirectHTTPRoute(nil, withQuery, k8sClient.Scheme())
+			Expect(err).To(HaveOccurred())
 		})
 	})
 })
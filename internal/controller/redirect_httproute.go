@@ -0,0 +1,218 @@
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// HTTPRouteGroupVersionKind is the kind of the Gateway API HTTPRoutes serving redirects
+var HTTPRouteGroupVersionKind = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// HTTPRouteListGroupVersionKind is the kind of lists of Gateway API HTTPRoutes
+var HTTPRouteListGroupVersionKind = HTTPRouteGroupVersionKind.GroupVersion().WithKind("HTTPRouteList")
+
+// httpRouteSpec mirrors the parts of the Gateway API HTTPRouteSpec redirects use, so HTTPRoutes can be
+// rendered without depending on the Gateway API module. Fields the API server defaults are always set,
+// so a rendered HTTPRoute equals the stored one unless it drifted.
+type httpRouteSpec struct {
+	ParentRefs []httpRouteParentRef `json:"parentRefs,omitempty"`
+	Hostnames  []string             `json:"hostnames,omitempty"`
+	Rules      []httpRouteRule      `json:"rules"`
+}
+
+type httpRouteParentRef struct {
+	Group       string `json:"group"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace,omitempty"`
+	Name        string `json:"name"`
+	SectionName string `json:"sectionName,omitempty"`
+	Port        int32  `json:"port,omitempty"`
+}
+
+type httpRouteRule struct {
+	Matches []httpRouteMatch  `json:"matches"`
+	Filters []httpRouteFilter `json:"filters"`
+}
+
+type httpRouteMatch struct {
+	Path httpRoutePathMatch `json:"path"`
+}
+
+type httpRoutePathMatch struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type httpRouteFilter struct {
+	Type            string                    `json:"type"`
+	RequestRedirect *httpRouteRequestRedirect `json:"requestRedirect,omitempty"`
+}
+
+type httpRouteRequestRedirect struct {
+	Scheme     string                 `json:"scheme,omitempty"`
+	Hostname   string                 `json:"hostname,omitempty"`
+	Path       *httpRoutePathModifier `json:"path,omitempty"`
+	Port       int32                  `json:"port,omitempty"`
+	StatusCode int                    `json:"statusCode"`
+}
+
+type httpRoutePathModifier struct {
+	Type               string `json:"type"`
+	ReplaceFullPath    string `json:"replaceFullPath,omitempty"`
+	ReplacePrefixMatch string `json:"replacePrefixMatch,omitempty"`
+}
+
+// UpdateRedirectHTTPRoute takes an existing HTTPRoute and updates it, or creates an entirely new HTTPRoute,
+// which redirects all requests for the source of the redirect to its target using a RequestRedirect filter
+func UpdateRedirectHTTPRoute(route *unstructured.Unstructured, redirect *v1alpha1.Redirect, scheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	if route == nil {
+		route = &unstructured.Unstructured{}
+	}
+
+	requestRedirect, err := httpRouteRequestRedirectFor(redirect)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := httpRouteSpec{
+		Hostnames: []string{redirect.Spec.Source},
+		Rules: []httpRouteRule{
+			{
+				Matches: []httpRouteMatch{
+					{
+						Path: httpRoutePathMatch{Type: "PathPrefix", Value: "/"},
+					},
+				},
+				Filters: []httpRouteFilter{
+					{
+						Type:            "RequestRedirect",
+						RequestRedirect: requestRedirect,
+					},
+				},
+			},
+		},
+	}
+
+	for _, parentRef := range redirect.Spec.ParentRefs {
+		ref := httpRouteParentRef{
+			Group:       parentRef.Group,
+			Kind:        parentRef.Kind,
+			Namespace:   parentRef.Namespace,
+			Name:        parentRef.Name,
+			SectionName: parentRef.SectionName,
+			Port:        parentRef.Port,
+		}
+
+		if ref.Group == "" {
+			ref.Group = HTTPRouteGroupVersionKind.Group
+		}
+
+		if ref.Kind == "" {
+			ref.Kind = "Gateway"
+		}
+
+		spec.ParentRefs = append(spec.ParentRefs, ref)
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert HTTPRoute spec")
+	}
+
+	route.SetGroupVersionKind(HTTPRouteGroupVersionKind)
+	route.SetName(redirect.Name)
+	route.SetNamespace(redirect.Namespace)
+	route.SetLabels(GetLabelsForRedirect(redirect.Name))
+	route.Object["spec"] = content
+
+	// Set Redirect instance as the owner and api
+	if err := ctrl.SetControllerReference(redirect, route, scheme); err != nil {
+		return nil, errors.Wrap(err, "Failed to set controller reference")
+	}
+
+	return route, nil
+}
+
+// httpRouteRequestRedirectFor returns the RequestRedirect filter redirecting to the target of the redirect.
+// Just like the Ingress backend, targets with scheme replace the whole URL, while targets without scheme
+// keep the path of the request.
+func httpRouteRequestRedirectFor(redirect *v1alpha1.Redirect) (*httpRouteRequestRedirect, error) {
+	target := redirect.Spec.Target
+	keepPath := !strings.Contains(target, "://")
+	if keepPath {
+		target = "http://" + target
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid redirect target %s", redirect.Spec.Target)
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("HTTPRoute redirects only support http and https targets, not %s", redirect.Spec.Target)
+	}
+
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("redirect target %s has no host", redirect.Spec.Target)
+	}
+
+	if parsed.RawQuery != "" || parsed.Fragment != "" {
+		return nil, fmt.Errorf("HTTPRoute redirects cannot set the query or fragment of target %s", redirect.Spec.Target)
+	}
+
+	if !slices.Contains(v1alpha1.RedirectHTTPRouteCodes, redirect.Spec.Code) {
+		return nil, fmt.Errorf("HTTPRoute redirects only support the codes 301 and 302, not %d", redirect.Spec.Code)
+	}
+
+	requestRedirect := &httpRouteRequestRedirect{
+		Scheme:     parsed.Scheme,
+		Hostname:   parsed.Hostname(),
+		StatusCode: redirect.Spec.Code,
+	}
+
+	if port := parsed.Port(); port != "" {
+		number, err := strconv.ParseInt(port, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid port of redirect target %s", redirect.Spec.Target)
+		}
+
+		requestRedirect.Port = int32(number)
+	}
+
+	switch {
+	case !keepPath:
+		path := parsed.Path
+		if path == "" {
+			path = "/"
+		}
+
+		requestRedirect.Path = &httpRoutePathModifier{Type: "ReplaceFullPath", ReplaceFullPath: path}
+
+	case parsed.Path != "" && parsed.Path != "/":
+		requestRedirect.Path = &httpRoutePathModifier{Type: "ReplacePrefixMatch", ReplacePrefixMatch: parsed.Path}
+	}
+
+	return requestRedirect, nil
+}
+
+// GetHTTPRouteNames returns the names of the given HTTPRoutes
+func GetHTTPRouteNames(routes []unstructured.Unstructured) []string {
+	var routeNames []string
+
+	for _, route := range routes {
+		routeNames = append(routeNames, route.GetName())
+	}
+
+	return routeNames
+}
@@ -5,33 +5,31 @@ import (
 	"regexp"
 	"strings"
 
-	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	networkingv1 "k8s.io/api/networking/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/spechtlabs/urlshortener/api/v1alpha1"
 )
 
-// UpdateRedirectIngress takes an existing ingress and updates it, or creates an entirely new *networkingv1.Ingress object
-func UpdateRedirectIngress(ing *networkingv1.Ingress, redirect *v1alpha1.Redirect, scheme *runtime.Scheme) *networkingv1.Ingress {
+// UpdateRedirectIngress takes an existing ingress and updates it, or creates an entirely new *networkingv1.Ingress object.
+// The metadata the API server manages, such as the resource version, is kept, so the ingress can be updated in place.
+func UpdateRedirectIngress(ing *networkingv1.Ingress, redirect *v1alpha1.Redirect, scheme *runtime.Scheme) (*networkingv1.Ingress, error) {
 	pathTypePrefix := networkingv1.PathTypePrefix
 
 	if ing == nil {
 		ing = &networkingv1.Ingress{}
 	}
 
-	ing.ObjectMeta = metav1.ObjectMeta{
-		Name:      redirect.Name,
-		Namespace: redirect.Namespace,
-		Labels:    GetLabelsForRedirect(redirect.Name),
-		Annotations: map[string]string{
-			"nginx.ingress.kubernetes.io/rewrite-target":          "/",
-			"nginx.ingress.kubernetes.io/permanent-redirect":      normalizeUrl(redirect.Spec.Target),
-			"nginx.ingress.kubernetes.io/permanent-redirect-code": fmt.Sprintf("%d", redirect.Spec.Code),
-		},
+	ing.Name = redirect.Name
+	ing.Namespace = redirect.Namespace
+	ing.Labels = GetLabelsForRedirect(redirect.Name)
+	ing.Annotations = map[string]string{
+		"nginx.ingress.kubernetes.io/rewrite-target":          "/",
+		"nginx.ingress.kubernetes.io/permanent-redirect":      normalizeUrl(redirect.Spec.Target),
+		"nginx.ingress.kubernetes.io/permanent-redirect-code": fmt.Sprintf("%d", redirect.Spec.Code),
 	}
 
 	ing.Spec = networkingv1.IngressSpec{
@@ -65,7 +63,7 @@ func UpdateRedirectIngress(ing *networkingv1.Ingress, redirect *v1alpha1.Redirec
 		ing.Spec.TLS = []networkingv1.IngressTLS{
 			{
 				Hosts:      []string{redirect.Spec.Source},
-				SecretName: fmt.Sprintf("%s-redirect-secret", strings.ReplaceAll(redirect.Spec.Source, ".", "-")),
+				SecretName: GetTLSSecretName(redirect),
 			},
 		}
 
@@ -77,12 +75,20 @@ func UpdateRedirectIngress(ing *networkingv1.Ingress, redirect *v1alpha1.Redirec
 
 	// Set Redirect instance as the owner and api
 	if err := ctrl.SetControllerReference(redirect, ing, scheme); err != nil {
-		otelzap.L().WithError(err).Error("Failed to set controller reference")
+		return nil, errors.Wrap(err, "Failed to set controller reference")
 	}
 
-	return ing
+	return ing, nil
+}
+
+// GetTLSSecretName returns the name of the Secret holding the TLS certificate of the given redirect
+func GetTLSSecretName(redirect *v1alpha1.Redirect) string {
+	return fmt.Sprintf("%s-redirect-secret", secretNameReplacer.Replace(redirect.Spec.Source))
 }
 
+// secretNameReplacer turns a source such as *.example.com into a valid part of a Secret name
+var secretNameReplacer = strings.NewReplacer(".", "-", "*", "wildcard")
+
 // GetLabelsForRedirect returns the labels for selecting the resources
 // belonging to the given redirect CRD name.
 func GetLabelsForRedirect(name string) map[string]string {
@@ -18,15 +18,19 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	"github.com/spechtlabs/go-otel-utils/otelzap"
 
@@ -38,13 +42,16 @@ import (
 type ShortlinkReconciler struct {
 	client *shortlinkclient.ShortlinkClient
 	scheme *runtime.Scheme
+	prober *TargetProber
 }
 
-// NewShortLinkReconciler returns a new ShortLinkReconciler
-func NewShortLinkReconciler(client client.Client, scheme *runtime.Scheme) *ShortlinkReconciler {
+// NewShortLinkReconciler returns a new ShortLinkReconciler.
+// The prober checks the targets of the Shortlinks periodically, which is disabled if it is nil.
+func NewShortLinkReconciler(client client.Client, scheme *runtime.Scheme, prober *TargetProber) *ShortlinkReconciler {
 	return &ShortlinkReconciler{
 		client: shortlinkclient.NewShortlinkClient(client),
 		scheme: scheme,
+		prober: prober,
 	}
 }
 
@@ -70,6 +77,35 @@ func (r *ShortlinkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	span.SetAttributes(attribute.String("shortlink", req.Name))
 
+	if shortlinkList, err := r.client.ListNamespaced(ctx, req.Namespace); shortlinkList != nil && err == nil {
+		active.WithLabelValues("shortlink").Set(float64(len(shortlinkList.Items)))
+
+		for _, shortlink := range shortlinkList.Items {
+			shortlinkInvocations.WithLabelValues(
+				shortlink.Name,
+				shortlink.Namespace,
+			).Set(float64(shortlink.Status.Count))
+
+			for variant, count := range shortlink.Status.VariantCounts {
+				shortlinkVariantInvocations.WithLabelValues(
+					shortlink.Name,
+					shortlink.Namespace,
+					variant,
+				).Set(float64(count))
+			}
+
+			broken := 0.0
+			if meta.IsStatusConditionFalse(shortlink.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionTargetReachable) {
+				broken = 1
+			}
+
+			shortlinkBrokenTargets.WithLabelValues(
+				shortlink.Name,
+				shortlink.Namespace,
+			).Set(broken)
+		}
+	}
+
 	// Get ShortLink from etcd
 	shortlink, err := r.client.GetNamespaced(ctx, req.NamespacedName)
 	if err != nil || shortlink == nil {
@@ -78,32 +114,296 @@ func (r *ShortlinkReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 				zap.String("name", "reconciler"),
 				zap.String("shortlink", req.String()),
 			)
-		} else {
-			otelzap.L().WithError(err).Ctx(ctx).Error("Failed to fetch ShortLink resource",
+
+			if r.prober != nil {
+				r.prober.forget(req.NamespacedName)
+			}
+			return ctrl.Result{}, nil
+		}
+
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to fetch ShortLink resource",
+			zap.String("name", "reconciler"),
+			zap.String("shortlink", req.String()),
+		)
+		return ctrl.Result{}, err
+	}
+
+	now := time.Now()
+
+	// Evaluate all of them, as each of them may change the status
+	expiryChanged := r.updateExpiryCondition(shortlink, now)
+	scheduleChanged := r.updateScheduleStatus(shortlink, now)
+	targetChanged := r.updateTargetCondition(ctx, shortlink, now)
+
+	if expiryChanged || scheduleChanged || targetChanged {
+		if err := r.client.UpdateStatus(ctx, shortlink); err != nil {
+			otelzap.L().WithError(err).Ctx(ctx).Error("Failed to update Shortlink status",
 				zap.String("name", "reconciler"),
 				zap.String("shortlink", req.String()),
 			)
+			return ctrl.Result{}, err
 		}
 	}
 
-	if shortlinkList, err := r.client.ListNamespaced(ctx, req.Namespace); shortlinkList != nil && err == nil {
-		active.WithLabelValues("shortlink").Set(float64(len(shortlinkList.Items)))
+	// Expired shortlinks are deleted once their grace period is over
+	if deleteAt := expiredShortlinkDeletionTime(shortlink, now); deleteAt != nil && !now.Before(*deleteAt) {
+		otelzap.L().Ctx(ctx).Info("Deleting expired Shortlink",
+			zap.String("name", "reconciler"),
+			zap.String("shortlink", req.String()),
+			zap.String("reason", shortlink.ExpiryReason(now)),
+		)
 
-		for _, shortlink := range shortlinkList.Items {
-			shortlinkInvocations.WithLabelValues(
-				shortlink.Name,
-				shortlink.Namespace,
-			).Set(float64(shortlink.Status.Count))
+		if err := r.client.Delete(ctx, shortlink); err != nil && !errors.IsNotFound(err) {
+			otelzap.L().WithError(err).Ctx(ctx).Error("Failed to delete expired Shortlink",
+				zap.String("name", "reconciler"),
+				zap.String("shortlink", req.String()),
+			)
+			return ctrl.Result{}, err
 		}
+
+		return ctrl.Result{}, nil
+	}
+
+	requeueAfter := nextShortlinkBoundary(shortlink, now)
+	if nextProbe := r.nextTargetProbe(shortlink, now); nextProbe > 0 && (requeueAfter == 0 || nextProbe < requeueAfter) {
+		requeueAfter = nextProbe
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// updateTargetCondition probes the live target of the Shortlink if the last probe is older than the probe interval,
+// records the result in the TargetReachable condition, the status code and the time of the probe and returns true if it probed.
+// The live target is the target of the active schedule entry, or Target. While it is down, the fallback target is probed as well.
+// The target is only probed once per probe interval, which limits how often the status is written.
+func (r *ShortlinkReconciler) updateTargetCondition(ctx context.Context, shortlink *urlshortenerv1alpha1.Shortlink, now time.Time) bool {
+	target, _ := shortlink.ActiveTarget(now)
+	if !r.isTargetProbeDue(shortlink, target, now) {
+		return false
+	}
+
+	condition := metav1.Condition{
+		Type:               urlshortenerv1alpha1.ShortlinkConditionTargetReachable,
+		Status:             metav1.ConditionTrue,
+		Reason:             urlshortenerv1alpha1.ShortlinkReasonTargetResponded,
+		ObservedGeneration: shortlink.Generation,
+	}
+
+	statusCode, err := r.prober.Probe(ctx, target)
+	r.prober.recordProbe(client.ObjectKeyFromObject(shortlink), target, now)
+
+	switch {
+	case isProbeRefused(err):
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = urlshortenerv1alpha1.ShortlinkReasonTargetNotProbed
+		condition.Message = err.Error()
+
+	case err != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = urlshortenerv1alpha1.ShortlinkReasonTargetUnreachable
+		condition.Message = err.Error()
+
+	case isProbeFailure(statusCode):
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = urlshortenerv1alpha1.ShortlinkReasonTargetFailed
+		condition.Message = fmt.Sprintf("Target answered with status code %d", statusCode)
+
+	default:
+		condition.Message = fmt.Sprintf("Target answered with status code %d", statusCode)
+	}
+
+	if condition.Status == metav1.ConditionFalse {
+		// Visitors are sent to the fallback target now, so it has to be reachable instead
+		if fallback := shortlink.Spec.FallbackTarget; fallback != "" && !urlshortenerv1alpha1.IsTargetTemplate(fallback) {
+			fallbackStatusCode, fallbackErr := r.prober.Probe(ctx, fallback)
+			switch {
+			case fallbackErr != nil:
+				condition.Message += fmt.Sprintf(", fallback target: %v", fallbackErr)
+			default:
+				condition.Message += fmt.Sprintf(", fallback target answered with status code %d", fallbackStatusCode)
+			}
+		}
+
+		otelzap.L().Ctx(ctx).Info("Shortlink target is not reachable",
+			zap.String("name", "reconciler"),
+			zap.String("shortlink", shortlink.Name),
+			zap.String("target", target),
+			zap.String("reason", condition.Message),
+		)
+	}
+
+	meta.SetStatusCondition(&shortlink.Status.Conditions, condition)
+	shortlink.Status.TargetStatusCode = statusCode
+	shortlink.Status.TargetCheckedAt = &metav1.Time{Time: now}
+
+	return true
+}
+
+// isTargetProbeDue returns true if the live target of the Shortlink changed or was not probed within the probe interval
+func (r *ShortlinkReconciler) isTargetProbeDue(shortlink *urlshortenerv1alpha1.Shortlink, target string, now time.Time) bool {
+	// Templated targets are only complete once a request fills in their placeholders
+	if r.prober == nil || r.prober.interval <= 0 || target == "" || urlshortenerv1alpha1.IsTargetTemplate(target) {
+		return false
+	}
+
+	condition := meta.FindStatusCondition(shortlink.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionTargetReachable)
+	if condition == nil || condition.ObservedGeneration != shortlink.Generation {
+		return true
+	}
+
+	lastProbe, ok := r.lastTargetProbe(shortlink)
+	if !ok {
+		return true
+	}
+
+	// The schedule may have switched the live target since the last probe
+	if lastProbe.target != "" && lastProbe.target != target {
+		return true
+	}
+
+	return !now.Before(lastProbe.at.Add(r.prober.interval))
+}
+
+// nextTargetProbe returns how long to wait until the target of the Shortlink is probed again, or 0 if it is not probed
+func (r *ShortlinkReconciler) nextTargetProbe(shortlink *urlshortenerv1alpha1.Shortlink, now time.Time) time.Duration {
+	if r.prober == nil || r.prober.interval <= 0 {
+		return 0
+	}
+
+	lastProbe, ok := r.lastTargetProbe(shortlink)
+	if !ok {
+		return 0
+	}
+
+	return max(time.Second, lastProbe.at.Add(r.prober.interval).Sub(now))
+}
+
+// lastTargetProbe returns the last probe of the Shortlink. Before this replica probed it, the time of the last probe
+// recorded in the status is used, whose target is unknown.
+func (r *ShortlinkReconciler) lastTargetProbe(shortlink *urlshortenerv1alpha1.Shortlink) (probeRecord, bool) {
+	if lastProbe, ok := r.prober.lastProbe(client.ObjectKeyFromObject(shortlink)); ok {
+		return lastProbe, true
+	}
+
+	if shortlink.Status.TargetCheckedAt == nil {
+		return probeRecord{}, false
+	}
+
+	return probeRecord{at: shortlink.Status.TargetCheckedAt.Time}, true
+}
+
+// updateExpiryCondition sets the Expired condition of the Shortlink and returns true if it changed.
+// Shortlinks that can never expire have no Expired condition.
+func (r *ShortlinkReconciler) updateExpiryCondition(shortlink *urlshortenerv1alpha1.Shortlink, now time.Time) bool {
+	if shortlink.Spec.ExpiresAt == nil && shortlink.Spec.MaxClicks <= 0 {
+		return meta.RemoveStatusCondition(&shortlink.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionExpired)
+	}
+
+	condition := metav1.Condition{
+		Type:               urlshortenerv1alpha1.ShortlinkConditionExpired,
+		Status:             metav1.ConditionFalse,
+		Reason:             urlshortenerv1alpha1.ShortlinkReasonActive,
+		Message:            "Shortlink is active",
+		ObservedGeneration: shortlink.Generation,
+	}
+
+	if reason := shortlink.ExpiryReason(now); reason != "" {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = reason
+		condition.Message = "Shortlink is expired"
+	}
+
+	return meta.SetStatusCondition(&shortlink.Status.Conditions, condition)
+}
+
+// updateScheduleStatus sets the current and next target of the Shortlink and returns true if they changed
+func (r *ShortlinkReconciler) updateScheduleStatus(shortlink *urlshortenerv1alpha1.Shortlink, now time.Time) bool {
+	currentTarget, _ := shortlink.ActiveTarget(now)
+
+	nextTarget := ""
+	var nextTransition *metav1.Time
+	if next := shortlink.NextScheduleEntry(now); next != nil {
+		nextTarget = next.Target
+		nextTransition = next.At.DeepCopy()
+	}
+
+	changed := shortlink.Status.CurrentTarget != currentTarget ||
+		shortlink.Status.NextTarget != nextTarget ||
+		!shortlink.Status.NextTransition.Equal(nextTransition)
+
+	shortlink.Status.CurrentTarget = currentTarget
+	shortlink.Status.NextTarget = nextTarget
+	shortlink.Status.NextTransition = nextTransition
+
+	return changed
+}
+
+// expiredShortlinkDeletionTime returns the point in time at which an expired Shortlink is deleted,
+// or nil if the Shortlink is not expired or should be kept
+func expiredShortlinkDeletionTime(shortlink *urlshortenerv1alpha1.Shortlink, now time.Time) *time.Time {
+	reason := shortlink.ExpiryReason(now)
+	if reason == "" || shortlink.Spec.DeleteAfterExpiry == nil {
+		return nil
+	}
+
+	expiredSince := now
+	if reason == urlshortenerv1alpha1.ShortlinkReasonExpiryDateReached {
+		expiredSince = shortlink.Spec.ExpiresAt.Time
+	} else if condition := meta.FindStatusCondition(shortlink.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionExpired); condition != nil {
+		expiredSince = condition.LastTransitionTime.Time
+	}
+
+	deleteAt := expiredSince.Add(shortlink.Spec.DeleteAfterExpiry.Duration)
+	return &deleteAt
+}
+
+// nextShortlinkBoundary returns how long to wait until the next point in time at which the
+// behaviour of the Shortlink changes, or 0 if there is none
+func nextShortlinkBoundary(shortlink *urlshortenerv1alpha1.Shortlink, now time.Time) time.Duration {
+	boundaries := make([]time.Time, 0)
+
+	if shortlink.Spec.NotBefore != nil {
+		boundaries = append(boundaries, shortlink.Spec.NotBefore.Time)
+	}
+
+	if next := shortlink.NextScheduleEntry(now); next != nil {
+		boundaries = append(boundaries, next.At.Time)
+	}
+
+	if shortlink.Spec.ExpiresAt != nil {
+		boundaries = append(boundaries, shortlink.Spec.ExpiresAt.Time)
+	}
+
+	if deleteAt := expiredShortlinkDeletionTime(shortlink, now); deleteAt != nil {
+		boundaries = append(boundaries, *deleteAt)
+	}
+
+	var requeueAfter time.Duration
+	for _, boundary := range boundaries {
+		if !boundary.After(now) {
+			continue
+		}
+
+		if until := boundary.Sub(now); requeueAfter == 0 || until < requeueAfter {
+			requeueAfter = until
+		}
+	}
+
+	return requeueAfter
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ShortlinkReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	options := controller.Options{}
+
+	// Probing blocks the reconciler until the target answers, so reconcile as many Shortlinks as may be probed at once
+	if r.prober != nil {
+		options.MaxConcurrentReconciles = cap(r.prober.semaphore)
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&urlshortenerv1alpha1.Shortlink{}).
+		WithOptions(options).
 		Named("shortlink").
 		Complete(r)
 }
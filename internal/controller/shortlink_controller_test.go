@@ -18,10 +18,15 @@ package controller
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
@@ -68,7 +73,7 @@ var _ = Describe("Shortlink Controller", func() {
 		})
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
-			controllerReconciler := NewShortLinkReconciler(k8sClient, k8sClient.Scheme())
+			controllerReconciler := NewShortLinkReconciler(k8sClient, k8sClient.Scheme(), nil)
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
@@ -78,4 +83,125 @@ var _ = Describe("Shortlink Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When probing the target of a resource", func() {
+		const resourceName = "probed-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		var target *httptest.Server
+
+		BeforeEach(func() {
+			target = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}))
+
+			resource := &urlshortenerv1alpha1.Shortlink{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: urlshortenerv1alpha1.ShortlinkSpec{
+					Target: target.URL,
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			target.Close()
+
+			resource := &urlshortenerv1alpha1.Shortlink{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should mark a broken target as not reachable", func() {
+			prober := NewTargetProber(time.Minute, time.Second, 1, nil)
+			// The test server listens on a loopback address, which the prober refuses to connect to otherwise
+			prober.client.Transport = &http.Transport{}
+			controllerReconciler := NewShortLinkReconciler(k8sClient, k8sClient.Scheme(), prober)
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+
+			resource := &urlshortenerv1alpha1.Shortlink{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.TargetStatusCode).To(Equal(http.StatusNotFound))
+			Expect(resource.Status.TargetCheckedAt).NotTo(BeNil())
+
+			condition := meta.FindStatusCondition(resource.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionTargetReachable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(urlshortenerv1alpha1.ShortlinkReasonTargetFailed))
+		})
+
+		It("should record the result of every probe", func() {
+			var statusCode atomic.Int32
+			statusCode.Store(http.StatusNotFound)
+
+			changing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(int(statusCode.Load()))
+			}))
+			defer changing.Close()
+
+			resource := &urlshortenerv1alpha1.Shortlink{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			resource.Spec.Target = changing.URL
+			Expect(k8sClient.Update(ctx, resource)).To(Succeed())
+
+			prober := NewTargetProber(time.Millisecond, time.Second, 1, nil)
+			prober.client.Transport = &http.Transport{}
+			controllerReconciler := NewShortLinkReconciler(k8sClient, k8sClient.Scheme(), prober)
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.TargetStatusCode).To(Equal(http.StatusNotFound))
+			firstCheck := resource.Status.TargetCheckedAt.DeepCopy()
+
+			By("recording the status code of a probe that does not change the condition")
+			statusCode.Store(http.StatusInternalServerError)
+			time.Sleep(10 * time.Millisecond)
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.TargetStatusCode).To(Equal(http.StatusInternalServerError))
+			Expect(resource.Status.TargetCheckedAt.Before(firstCheck)).To(BeFalse())
+
+			condition := meta.FindStatusCondition(resource.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionTargetReachable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Message).To(ContainSubstring("500"))
+		})
+
+		It("should not probe targets on private addresses", func() {
+			controllerReconciler := NewShortLinkReconciler(k8sClient, k8sClient.Scheme(), NewTargetProber(time.Minute, time.Second, 1, nil))
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			resource := &urlshortenerv1alpha1.Shortlink{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(resource.Status.TargetStatusCode).To(BeZero())
+			Expect(resource.IsTargetReachable()).To(BeTrue())
+
+			condition := meta.FindStatusCondition(resource.Status.Conditions, urlshortenerv1alpha1.ShortlinkConditionTargetReachable)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionUnknown))
+			Expect(condition.Reason).To(Equal(urlshortenerv1alpha1.ShortlinkReasonTargetNotProbed))
+		})
+	})
 })
@@ -0,0 +1,390 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+
+	v1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
+	shortlinkclient "github.com/spechtlabs/urlshortener/pkg/client"
+)
+
+// ShortlinkResolver keeps an indexed in-memory view of all Shortlinks in the current namespace.
+// The view is kept current by the watch events of the manager's Shortlink informer, so resolving
+// a shortlink on the redirect path is a map read without any file or network I/O.
+// Besides their exact name, Shortlinks are indexed by the normalized form of their name and aliases.
+// Shortlinks bound to domains are only resolved for requests to these hosts, and take precedence
+// over Shortlinks without domains of the same name.
+type ShortlinkResolver struct {
+	cache  cache.Cache
+	tracer trace.Tracer
+
+	namespace    string
+	registration toolscache.ResourceEventHandlerRegistration
+
+	mu         sync.RWMutex
+	shortlinks map[string]*v1alpha1.Shortlink
+	names      map[string][]string
+}
+
+// NewShortlinkResolver returns a new ShortlinkResolver backed by the given informer cache
+func NewShortlinkResolver(cache cache.Cache) *ShortlinkResolver {
+	return &ShortlinkResolver{
+		cache:      cache,
+		tracer:     otel.Tracer("urlshortener"),
+		shortlinks: make(map[string]*v1alpha1.Shortlink),
+		names:      make(map[string][]string),
+	}
+}
+
+// Start registers the resolver with the Shortlink informer and blocks until the context is done.
+// It implements manager.Runnable so the resolver can be added to the controller manager.
+func (r *ShortlinkResolver) Start(ctx context.Context) error {
+	// try to read the namespace from /var/run
+	namespace, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return errors.Wrap(err, "Unable to read current namespace")
+	}
+
+	informer, err := r.cache.GetInformer(ctx, &v1alpha1.Shortlink{})
+	if err != nil {
+		return errors.Wrap(err, "Unable to get Shortlink informer")
+	}
+
+	r.mu.Lock()
+	r.namespace = strings.TrimSpace(string(namespace))
+	r.mu.Unlock()
+
+	registration, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    r.upsert,
+		UpdateFunc: func(_, newObj interface{}) { r.upsert(newObj) },
+		DeleteFunc: r.delete,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to register Shortlink event handler")
+	}
+
+	r.mu.Lock()
+	r.registration = registration
+	r.mu.Unlock()
+
+	otelzap.L().Ctx(ctx).Info("Shortlink resolver started", zap.String("namespace", r.namespace))
+
+	<-ctx.Done()
+
+	if err := informer.RemoveEventHandler(registration); err != nil {
+		otelzap.L().WithError(err).Warn("Failed to remove Shortlink event handler")
+	}
+
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica serves
+// redirects, so every replica needs its own resolver regardless of leadership.
+func (r *ShortlinkResolver) NeedLeaderElection() bool {
+	return false
+}
+
+// HasSynced returns true once the resolver has received the initial list of Shortlinks
+func (r *ShortlinkResolver) HasSynced() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.registration != nil && r.registration.HasSynced()
+}
+
+// ReadyzCheck reports the resolver as not ready until its cache has synced
+func (r *ShortlinkResolver) ReadyzCheck(_ *http.Request) error {
+	if !r.HasSynced() {
+		return errors.New("shortlink resolver cache has not synced yet")
+	}
+
+	return nil
+}
+
+// Resolve returns a copy of the Shortlink with the given name in the current namespace, served under the given host.
+// If no Shortlink has exactly this name, the name is matched against the normalized names, aliases
+// and paths of all Shortlinks. If no such Shortlink exists, a Kubernetes NotFound error is returned.
+// An empty host matches Shortlinks under every domain, so an error is returned if several of them match the name.
+func (r *ShortlinkResolver) Resolve(ct context.Context, host string, name string) (*v1alpha1.Shortlink, error) {
+	_, span := r.tracer.Start(ct, "ShortlinkResolver.Resolve", trace.WithAttributes(attribute.String("host", host), attribute.String("name", name)))
+	defer span.End()
+
+	if !r.HasSynced() {
+		err := errors.New("shortlink resolver cache has not synced yet")
+		span.RecordError(err)
+		return nil, err
+	}
+
+	if host == "" {
+		if candidates := r.candidates(name); len(candidates) > 1 {
+			err := shortlinkclient.NewAmbiguousNameError(name, candidates)
+			span.RecordError(err)
+			return nil, err
+		}
+	}
+
+	shortlink, ok := r.lookup(host, name)
+	if !ok {
+		resolverLookups.WithLabelValues("miss").Inc()
+		return nil, k8serrors.NewNotFound(v1alpha1.GroupVersion.WithResource("shortlinks").GroupResource(), name)
+	}
+
+	resolverLookups.WithLabelValues("hit").Inc()
+	return shortlink.DeepCopy(), nil
+}
+
+// ResolvePath returns a copy of the Shortlink serving the longest prefix of the given request path under the given host,
+// together with the name or path it matched and the remaining path following it.
+// If no prefix of the path matches a Shortlink, a Kubernetes NotFound error is returned.
+func (r *ShortlinkResolver) ResolvePath(ct context.Context, host string, path string) (*v1alpha1.Shortlink, string, string, error) {
+	_, span := r.tracer.Start(ct, "ShortlinkResolver.ResolvePath", trace.WithAttributes(attribute.String("host", host), attribute.String("path", path)))
+	defer span.End()
+
+	if !r.HasSynced() {
+		err := errors.New("shortlink resolver cache has not synced yet")
+		span.RecordError(err)
+		return nil, "", "", err
+	}
+
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	for idx := len(segments); idx > 0; idx-- {
+		prefix := strings.Join(segments[:idx], "/")
+		if prefix == "" {
+			continue
+		}
+
+		if shortlink, ok := r.lookup(host, prefix); ok {
+			span.SetAttributes(attribute.String("match", prefix))
+			resolverLookups.WithLabelValues("hit").Inc()
+			return shortlink.DeepCopy(), prefix, strings.Join(segments[idx:], "/"), nil
+		}
+	}
+
+	resolverLookups.WithLabelValues("miss").Inc()
+	return nil, "", "", k8serrors.NewNotFound(v1alpha1.GroupVersion.WithResource("shortlinks").GroupResource(), path)
+}
+
+// lookup returns the Shortlink with the given name, or the first Shortlink whose normalized names match it.
+// Shortlinks bound to the host are preferred over Shortlinks without domains. An empty host matches any Shortlink.
+func (r *ShortlinkResolver) lookup(host string, name string) (*v1alpha1.Shortlink, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if host == "" {
+		return r.lookupMatching(name, func(*v1alpha1.Shortlink) bool { return true })
+	}
+
+	domain := v1alpha1.NormalizeDomain(host)
+	if shortlink, ok := r.lookupMatching(name, func(shortlink *v1alpha1.Shortlink) bool {
+		return slices.Contains(shortlink.Spec.Domains, domain)
+	}); ok {
+		return shortlink, true
+	}
+
+	return r.lookupMatching(name, func(shortlink *v1alpha1.Shortlink) bool {
+		return len(shortlink.Spec.Domains) == 0
+	})
+}
+
+// candidates returns the names of all Shortlinks the given name may resolve to without a host
+func (r *ShortlinkResolver) candidates(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.shortlinks[name]; ok {
+		return []string{name}
+	}
+
+	return slices.Clone(r.names[v1alpha1.NormalizeShortlinkName(name)])
+}
+
+// lookupMatching returns the Shortlink with the given name, or the first Shortlink whose normalized names match it,
+// considering only the Shortlinks for which matches returns true. r.mu must be held.
+func (r *ShortlinkResolver) lookupMatching(name string, matches func(*v1alpha1.Shortlink) bool) (*v1alpha1.Shortlink, bool) {
+	if shortlink, ok := r.shortlinks[name]; ok && matches(shortlink) {
+		return shortlink, true
+	}
+
+	// names are kept sorted, so conflicting aliases resolve deterministically
+	for _, candidate := range r.names[v1alpha1.NormalizeShortlinkName(name)] {
+		if shortlink, ok := r.shortlinks[candidate]; ok && matches(shortlink) {
+			return shortlink, true
+		}
+	}
+
+	return nil, false
+}
+
+// Suggest returns up to limit names or aliases of Shortlinks that are similar to the given name,
+// either by edit distance or by prefix, closest first. Only Shortlinks for which visible returns true are considered.
+func (r *ShortlinkResolver) Suggest(ct context.Context, name string, limit int, visible func(*v1alpha1.Shortlink) bool) []string {
+	_, span := r.tracer.Start(ct, "ShortlinkResolver.Suggest", trace.WithAttributes(attribute.String("name", name)))
+	defer span.End()
+
+	type suggestion struct {
+		name     string
+		distance int
+	}
+
+	normalized := v1alpha1.NormalizeShortlinkName(name)
+	maxDistance := max(1, len(normalized)/3)
+
+	r.mu.RLock()
+	suggestions := make([]suggestion, 0)
+	for _, shortlink := range r.shortlinks {
+		if !visible(shortlink) {
+			continue
+		}
+
+		// Only suggest the closest name of every Shortlink
+		best := suggestion{distance: -1}
+		for _, candidate := range shortlink.Names() {
+			key := v1alpha1.NormalizeShortlinkName(candidate)
+
+			distance := levenshtein(normalized, key)
+			isPrefix := len(normalized) >= 2 && (strings.HasPrefix(key, normalized) || strings.HasPrefix(normalized, key))
+			if distance > maxDistance && !isPrefix {
+				continue
+			}
+
+			if best.distance < 0 || distance < best.distance {
+				best = suggestion{name: candidate, distance: distance}
+			}
+		}
+
+		if best.distance >= 0 {
+			suggestions = append(suggestions, best)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(suggestions, func(a, b suggestion) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	names := make([]string, 0, min(limit, len(suggestions)))
+	for _, s := range suggestions[:min(limit, len(suggestions))] {
+		names = append(names, s.name)
+	}
+
+	span.SetAttributes(attribute.Int("suggestions", len(names)))
+	return names
+}
+
+func (r *ShortlinkResolver) upsert(obj interface{}) {
+	shortlink, ok := obj.(*v1alpha1.Shortlink)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if shortlink.Namespace != r.namespace {
+		return
+	}
+
+	if previous, ok := r.shortlinks[shortlink.Name]; ok {
+		r.unindexNames(previous)
+	}
+
+	r.shortlinks[shortlink.Name] = shortlink
+	r.indexNames(shortlink)
+	resolverCachedShortlinks.Set(float64(len(r.shortlinks)))
+}
+
+func (r *ShortlinkResolver) delete(obj interface{}) {
+	// the informer hands us a tombstone if it missed the delete event
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	shortlink, ok := obj.(*v1alpha1.Shortlink)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if shortlink.Namespace != r.namespace {
+		return
+	}
+
+	if previous, ok := r.shortlinks[shortlink.Name]; ok {
+		r.unindexNames(previous)
+	}
+
+	delete(r.shortlinks, shortlink.Name)
+	resolverCachedShortlinks.Set(float64(len(r.shortlinks)))
+}
+
+// indexNames adds the normalized name and aliases of the shortlink to the index. r.mu must be held.
+func (r *ShortlinkResolver) indexNames(shortlink *v1alpha1.Shortlink) {
+	for _, name := range shortlink.Names() {
+		key := v1alpha1.NormalizeShortlinkName(name)
+
+		idx, found := slices.BinarySearch(r.names[key], shortlink.Name)
+		if !found {
+			r.names[key] = slices.Insert(r.names[key], idx, shortlink.Name)
+		}
+	}
+}
+
+// unindexNames removes the normalized name and aliases of the shortlink from the index. r.mu must be held.
+func (r *ShortlinkResolver) unindexNames(shortlink *v1alpha1.Shortlink) {
+	for _, name := range shortlink.Names() {
+		key := v1alpha1.NormalizeShortlinkName(name)
+
+		r.names[key] = slices.DeleteFunc(r.names[key], func(candidate string) bool { return candidate == shortlink.Name })
+		if len(r.names[key]) == 0 {
+			delete(r.names, key)
+		}
+	}
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/spechtlabs/urlshortener/pkg/policy"
+)
+
+// maxProbeBodySize is the number of bytes read from the body of a GET probe, so connections can be reused
+const maxProbeBodySize = 4 << 10
+
+// errProbeRefused is returned for targets that are not probed, because the policy does not allow them
+// or they resolve to a private address the prober must not reach into
+var errProbeRefused = errors.New("target is not probed")
+
+// TargetProber checks whether the targets of Shortlinks are reachable. Every target is probed with a HEAD request,
+// falling back to GET for servers that do not answer HEAD properly. At most concurrency probes run at the same time.
+// The prober neither follows redirects nor connects to private addresses, so Shortlinks cannot use it to scan the cluster.
+type TargetProber struct {
+	client    *http.Client
+	policy    *policy.Policy
+	interval  time.Duration
+	semaphore chan struct{}
+
+	mu     sync.Mutex
+	probes map[types.NamespacedName]probeRecord
+}
+
+// probeRecord is the last probe of the live target of a Shortlink
+type probeRecord struct {
+	target string
+	at     time.Time
+}
+
+// NewTargetProber returns a new TargetProber that probes every target once per interval,
+// giving up on a single request after timeout. Targets the policy does not allow are not probed.
+func NewTargetProber(interval time.Duration, timeout time.Duration, concurrency int, targetPolicy *policy.Policy) *TargetProber {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: refusePrivateAddress,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the target on behalf of the prober, bypassing the address check of the dialer
+	transport.Proxy = nil
+
+	return &TargetProber{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// Redirects are answers of the target, following them would let the target send the prober anywhere
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		policy:    targetPolicy,
+		interval:  interval,
+		semaphore: make(chan struct{}, max(1, concurrency)),
+		probes:    make(map[types.NamespacedName]probeRecord),
+	}
+}
+
+// Probe requests the target and returns the status code it answered with.
+// An error is returned if the target did not answer at all, or if it is not probed, see isProbeRefused.
+func (p *TargetProber) Probe(ctx context.Context, target string) (int, error) {
+	if p.policy != nil {
+		if err := p.policy.CheckTarget(target); err != nil {
+			return 0, errors.Wrapf(errProbeRefused, "target is not allowed by policy: %v", err)
+		}
+	}
+
+	if !strings.HasPrefix(target, "http") {
+		target = "http://" + target
+	}
+
+	select {
+	case p.semaphore <- struct{}{}:
+		defer func() { <-p.semaphore }()
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	statusCode, err := p.request(ctx, http.MethodHead, target)
+	if isProbeRefused(err) || (err == nil && !isProbeFailure(statusCode)) {
+		return statusCode, err
+	}
+
+	// Many servers reject or mishandle HEAD, so only a failing GET counts
+	return p.request(ctx, http.MethodGet, target)
+}
+
+func (p *TargetProber) request(ctx context.Context, method string, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to create probe request")
+	}
+
+	req.Header.Set("User-Agent", "urlshortener-target-probe")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Unable to reach target %s", target)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.CopyN(io.Discard, resp.Body, maxProbeBodySize)
+
+	return resp.StatusCode, nil
+}
+
+// lastProbe returns the last probe of the Shortlink since the prober started
+func (p *TargetProber) lastProbe(key types.NamespacedName) (probeRecord, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	probe, ok := p.probes[key]
+	return probe, ok
+}
+
+// recordProbe remembers the probe of the target of the Shortlink, so it is not probed again before the interval passed
+func (p *TargetProber) recordProbe(key types.NamespacedName, target string, at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.probes[key] = probeRecord{target: target, at: at}
+}
+
+// forget drops the probes of a deleted Shortlink
+func (p *TargetProber) forget(key types.NamespacedName) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.probes, key)
+}
+
+// refusePrivateAddress is the Control function of the dialer of the prober. It runs once the target has been resolved,
+// so domains resolving to private addresses are refused just like private IP addresses.
+func refusePrivateAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || policy.IsPrivateIP(ip) {
+		return errors.Wrapf(errProbeRefused, "target resolves to the private address %s", host)
+	}
+
+	return nil
+}
+
+// isProbeRefused returns true if the error shows the target was not probed, rather than that it is unreachable
+func isProbeRefused(err error) bool {
+	return errors.Is(err, errProbeRefused)
+}
+
+// isProbeFailure returns true if the status code shows the target is broken.
+// Targets asking for authentication exist, so 401 and 403 are not failures.
+func isProbeFailure(statusCode int) bool {
+	return statusCode >= 400 && statusCode != http.StatusUnauthorized && statusCode != http.StatusForbidden
+}
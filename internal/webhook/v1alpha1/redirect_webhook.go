@@ -0,0 +1,280 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+
+	urlshortenerv1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// controllerAnnotations are the annotations the controller sets on the Ingress of a Redirect,
+// so TLS annotations must not override them
+var controllerAnnotations = []string{
+	"nginx.ingress.kubernetes.io/rewrite-target",
+	"nginx.ingress.kubernetes.io/permanent-redirect",
+	"nginx.ingress.kubernetes.io/permanent-redirect-code",
+}
+
+// issuerAnnotations are the cert-manager annotations selecting the issuer of the certificate. Only one of them may be set.
+var issuerAnnotations = []string{"cert-manager.io/issuer", "cert-manager.io/cluster-issuer"}
+
+// SetupRedirectWebhookWithManager registers the webhook for Redirect in the manager.
+// Redirects that do not select a backend are validated for the defaultBackend the controller serves them with.
+func SetupRedirectWebhookWithManager(mgr ctrl.Manager, defaultBackend urlshortenerv1alpha1.RedirectBackend) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&urlshortenerv1alpha1.Redirect{}).
+		WithValidator(&RedirectCustomValidator{client: mgr.GetClient(), defaultBackend: defaultBackend}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-urlshortener-cedi-dev-v1alpha1-redirect,mutating=false,failurePolicy=fail,sideEffects=None,groups=urlshortener.cedi.dev,resources=redirects,verbs=create;update,versions=v1alpha1,name=vredirect-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RedirectCustomValidator struct is responsible for validating the Redirect resource
+// when it is created, updated, or deleted.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type RedirectCustomValidator struct {
+	// client lists the Redirects of all namespaces to check no two of them claim the same source
+	client client.Reader
+
+	// defaultBackend serves the Redirects that do not select a backend themselves, or an Ingress if it is empty
+	defaultBackend urlshortenerv1alpha1.RedirectBackend
+}
+
+var _ webhook.CustomValidator = &RedirectCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Redirect.
+func (v *RedirectCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	redirect, ok := obj.(*urlshortenerv1alpha1.Redirect)
+	if !ok {
+		return nil, fmt.Errorf("expected a Redirect object but got %T", obj)
+	}
+
+	otelzap.L().Ctx(ctx).Debug("Validation for Redirect upon creation", zap.String("redirect", redirect.GetName()))
+
+	return nil, v.validateRedirect(ctx, redirect)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Redirect.
+func (v *RedirectCustomValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	redirect, ok := newObj.(*urlshortenerv1alpha1.Redirect)
+	if !ok {
+		return nil, fmt.Errorf("expected a Redirect object for the newObj but got %T", newObj)
+	}
+
+	otelzap.L().Ctx(ctx).Debug("Validation for Redirect upon update", zap.String("redirect", redirect.GetName()))
+
+	return nil, v.validateRedirect(ctx, redirect)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Redirect.
+// Redirects can always be deleted.
+func (v *RedirectCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *RedirectCustomValidator) validateRedirect(ctx context.Context, redirect *urlshortenerv1alpha1.Redirect) error {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+
+	allErrs = append(allErrs, validateSource(specPath.Child("source"), redirect.Spec.Source)...)
+
+	if !slices.Contains(urlshortenerv1alpha1.RedirectIngressCodes, redirect.Spec.Code) {
+		supported := make([]string, 0, len(urlshortenerv1alpha1.RedirectIngressCodes))
+		for _, code := range urlshortenerv1alpha1.RedirectIngressCodes {
+			supported = append(supported, fmt.Sprint(code))
+		}
+
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("code"), redirect.Spec.Code, supported))
+	}
+
+	allErrs = append(allErrs, validateTLS(specPath.Child("tls"), redirect.Spec.TLS)...)
+
+	if v.backend(redirect) == urlshortenerv1alpha1.RedirectBackendHTTPRoute {
+		allErrs = append(allErrs, validateHTTPRouteBackend(specPath, redirect.Spec)...)
+	}
+
+	if len(allErrs) == 0 {
+		sourceErrs, err := v.validateUniqueSource(ctx, redirect)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+
+		allErrs = append(allErrs, sourceErrs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: urlshortenerv1alpha1.GroupVersion.Group, Kind: "Redirect"},
+		redirect.Name,
+		allErrs,
+	)
+}
+
+// backend returns the backend the controller serves the redirect with
+func (v *RedirectCustomValidator) backend(redirect *urlshortenerv1alpha1.Redirect) urlshortenerv1alpha1.RedirectBackend {
+	if redirect.Spec.Backend != "" {
+		return redirect.Spec.Backend
+	}
+
+	if v.defaultBackend != "" {
+		return v.defaultBackend
+	}
+
+	return urlshortenerv1alpha1.RedirectBackendIngress
+}
+
+// validateSource checks the source is a host, or a wildcard host such as *.example.com, that can be used in an Ingress rule
+func validateSource(path *field.Path, source string) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	var msgs []string
+	if strings.HasPrefix(source, "*") {
+		msgs = validation.IsWildcardDNS1123Subdomain(source)
+	} else {
+		msgs = validation.IsDNS1123Subdomain(source)
+	}
+
+	for _, msg := range msgs {
+		allErrs = append(allErrs, field.Invalid(path, source, msg))
+	}
+
+	return allErrs
+}
+
+// validateTLS checks the TLS annotations are valid annotations, are only set if TLS is enabled,
+// do not override the annotations of the controller and select at most one cert-manager issuer
+func validateTLS(path *field.Path, tls urlshortenerv1alpha1.TLSSpec) field.ErrorList {
+	annotationsPath := path.Child("annotations")
+
+	if !tls.Enable && len(tls.Annotations) > 0 {
+		return field.ErrorList{field.Forbidden(annotationsPath, "TLS annotations are only applied if tls.enable is true")}
+	}
+
+	allErrs := apivalidation.ValidateAnnotations(tls.Annotations, annotationsPath)
+
+	issuers := make([]string, 0, len(issuerAnnotations))
+	for _, key := range issuerAnnotations {
+		value, ok := tls.Annotations[key]
+		if !ok {
+			continue
+		}
+
+		if strings.TrimSpace(value) == "" {
+			allErrs = append(allErrs, field.Invalid(annotationsPath.Key(key), value, "the name of the issuer must not be empty"))
+		}
+
+		issuers = append(issuers, key)
+	}
+
+	if len(issuers) > 1 {
+		allErrs = append(allErrs, field.Invalid(annotationsPath, strings.Join(issuers, ", "), "only one of the annotations may select the issuer of the certificate"))
+	}
+
+	for _, key := range controllerAnnotations {
+		if _, ok := tls.Annotations[key]; ok {
+			allErrs = append(allErrs, field.Forbidden(annotationsPath.Key(key), "the annotation is set by the controller from the Redirect"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateHTTPRouteBackend checks the HTTPRoute of the Redirect can attach to a Gateway, and that its code and target
+// can be expressed by a RequestRedirect filter, which only supports 301 and 302 and cannot set the query or fragment
+func validateHTTPRouteBackend(specPath *field.Path, spec urlshortenerv1alpha1.RedirectSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(spec.ParentRefs) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("parentRefs"), "the HTTPRoute backend needs a Gateway to attach to"))
+	}
+
+	if !slices.Contains(urlshortenerv1alpha1.RedirectHTTPRouteCodes, spec.Code) {
+		supported := make([]string, 0, len(urlshortenerv1alpha1.RedirectHTTPRouteCodes))
+		for _, code := range urlshortenerv1alpha1.RedirectHTTPRouteCodes {
+			supported = append(supported, fmt.Sprint(code))
+		}
+
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("code"), spec.Code, supported))
+	}
+
+	target := spec.Target
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+
+	parsed, err := url.Parse(target)
+	switch {
+	case err != nil:
+		allErrs = append(allErrs, field.Invalid(specPath.Child("target"), spec.Target, fmt.Sprintf("the target is not a valid URL: %v", err)))
+	case parsed.Scheme != "http" && parsed.Scheme != "https":
+		allErrs = append(allErrs, field.Invalid(specPath.Child("target"), spec.Target, "the HTTPRoute backend only redirects to http and https targets"))
+	case parsed.RawQuery != "" || parsed.Fragment != "":
+		allErrs = append(allErrs, field.Invalid(specPath.Child("target"), spec.Target, "the HTTPRoute backend cannot redirect to a query or fragment"))
+	}
+
+	return allErrs
+}
+
+// validateUniqueSource checks no Redirect of any namespace claims a host the source of the Redirect claims as well,
+// as their Ingresses would compete for the same host
+func (v *RedirectCustomValidator) validateUniqueSource(ctx context.Context, redirect *urlshortenerv1alpha1.Redirect) (field.ErrorList, error) {
+	if v.client == nil {
+		return nil, nil
+	}
+
+	redirects := &urlshortenerv1alpha1.RedirectList{}
+	if err := v.client.List(ctx, redirects); err != nil {
+		return nil, fmt.Errorf("unable to list redirects: %w", err)
+	}
+
+	for idx := range redirects.Items {
+		other := &redirects.Items[idx]
+		if other.Namespace == redirect.Namespace && other.Name == redirect.Name {
+			continue
+		}
+
+		if redirect.OverlapsSource(other) {
+			return field.ErrorList{field.Invalid(field.NewPath("spec", "source"), redirect.Spec.Source,
+				fmt.Sprintf("overlaps with the source %s of Redirect %s/%s", other.Spec.Source, other.Namespace, other.Name))}, nil
+		}
+	}
+
+	return nil, nil
+}
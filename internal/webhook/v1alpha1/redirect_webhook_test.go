@@ -0,0 +1,162 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	urlshortenerv1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+var _ = Describe("Redirect Webhook", func() {
+	var (
+		obj       *urlshortenerv1alpha1.Redirect
+		oldObj    *urlshortenerv1alpha1.Redirect
+		validator RedirectCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &urlshortenerv1alpha1.Redirect{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "docs",
+				Namespace: "default",
+			},
+			Spec: urlshortenerv1alpha1.RedirectSpec{
+				Source:           "docs.example.com",
+				Target:           "https://example.com/docs",
+				Code:             308,
+				IngressClassName: "nginx",
+			},
+		}
+		oldObj = obj.DeepCopy()
+		validator = RedirectCustomValidator{}
+	})
+
+	Context("When creating or updating Redirect under Validating Webhook", func() {
+		It("Should admit a valid Redirect", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny sources that are no hosts", func() {
+			obj.Spec.Source = "https://docs.example.com/"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.source")))
+		})
+
+		It("Should deny codes that do not redirect", func() {
+			obj.Spec.Code = 304
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.code")))
+		})
+
+		It("Should deny TLS annotations if TLS is disabled", func() {
+			obj.Spec.TLS.Annotations = map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("tls.enable")))
+		})
+
+		It("Should deny TLS annotations overriding the redirect", func() {
+			obj.Spec.TLS.Enable = true
+			obj.Spec.TLS.Annotations = map[string]string{"nginx.ingress.kubernetes.io/permanent-redirect": "https://example.org"}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("set by the controller")))
+		})
+
+		It("Should deny TLS annotations selecting more than one issuer", func() {
+			obj.Spec.TLS.Enable = true
+			obj.Spec.TLS.Annotations = map[string]string{
+				"cert-manager.io/issuer":         "selfsigned",
+				"cert-manager.io/cluster-issuer": "letsencrypt",
+			}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("only one of the annotations")))
+		})
+
+		It("Should deny HTTPRoute redirects without Gateway or to a query", func() {
+			obj.Spec.Backend = urlshortenerv1alpha1.RedirectBackendHTTPRoute
+			obj.Spec.Code = 301
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.parentRefs")))
+
+			obj.Spec.ParentRefs = []urlshortenerv1alpha1.ParentReference{{Name: "public", Namespace: "gateways"}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Target = "https://example.com/docs?lang=en"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("query or fragment")))
+		})
+
+		It("Should deny codes the HTTPRoute backend does not support", func() {
+			obj.Spec.Backend = urlshortenerv1alpha1.RedirectBackendHTTPRoute
+			obj.Spec.ParentRefs = []urlshortenerv1alpha1.ParentReference{{Name: "public", Namespace: "gateways"}}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.code")))
+
+			obj.Spec.Code = 302
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should validate Redirects without backend for the default backend", func() {
+			validator = RedirectCustomValidator{defaultBackend: urlshortenerv1alpha1.RedirectBackendHTTPRoute}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.parentRefs")))
+
+			obj.Spec.Backend = urlshortenerv1alpha1.RedirectBackendIngress
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When validating Redirect against the Redirects of all namespaces", func() {
+		It("Should deny sources claimed by another Redirect", func() {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "redirect-webhook"}}
+			Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+			existing := obj.DeepCopy()
+			existing.Namespace = namespace.Name
+			existing.Spec.Source = "*.example.com"
+			Expect(k8sClient.Create(ctx, existing)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, existing)).To(Succeed())
+			})
+
+			validator = RedirectCustomValidator{client: k8sClient}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("overlaps with the source *.example.com of Redirect redirect-webhook/docs")))
+
+			By("allowing sources more than one label below the wildcard")
+			obj.Spec.Source = "docs.eu.example.com"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			By("allowing the Redirect to be updated itself")
+			Expect(validator.ValidateUpdate(ctx, existing, existing)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
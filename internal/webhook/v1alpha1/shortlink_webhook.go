@@ -0,0 +1,328 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+
+	urlshortenerv1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
+	"github.com/spechtlabs/urlshortener/pkg/policy"
+	"github.com/spechtlabs/urlshortener/pkg/rules"
+)
+
+// ReservedNames are the first path segments the API server serves itself, so shortlinks cannot use them
+var ReservedNames = []string{"_", "api", "assets", "swagger"}
+
+// targetScheme matches the scheme of a target, or the host of a target such as localhost:8080 without scheme
+var targetScheme = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):`)
+
+// SetupShortlinkWebhookWithManager registers the webhook for Shortlink in the manager.
+// The targets of Shortlinks are checked against targetPolicy, so Shortlinks applied with kubectl follow the same policy as the API.
+func SetupShortlinkWebhookWithManager(mgr ctrl.Manager, targetPolicy *policy.Policy) error {
+	ruleEvaluator, err := rules.NewEvaluator()
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewWebhookManagedBy(mgr).For(&urlshortenerv1alpha1.Shortlink{}).
+		WithValidator(&ShortlinkCustomValidator{client: mgr.GetClient(), rules: ruleEvaluator, policy: targetPolicy}).
+		WithDefaulter(&ShortlinkCustomDefaulter{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-urlshortener-cedi-dev-v1alpha1-shortlink,mutating=true,failurePolicy=fail,sideEffects=None,groups=urlshortener.cedi.dev,resources=shortlinks,verbs=create;update,versions=v1alpha1,name=mshortlink-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ShortlinkCustomDefaulter struct is responsible for setting default values on the custom resource of the
+// Kind Shortlink when those are created or updated.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as it is used only for temporary operations and does not need to be deeply copied.
+type ShortlinkCustomDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &ShortlinkCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the Kind Shortlink.
+// It sets the Code of the Shortlink, and the RedirectAfter of HTML redirects.
+func (d *ShortlinkCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	shortlink, ok := obj.(*urlshortenerv1alpha1.Shortlink)
+	if !ok {
+		return fmt.Errorf("expected a Shortlink object but got %T", obj)
+	}
+
+	otelzap.L().Ctx(ctx).Debug("Defaulting for Shortlink", zap.String("shortlink", shortlink.GetName()))
+
+	if shortlink.Spec.Code == 0 {
+		shortlink.Spec.Code = urlshortenerv1alpha1.DefaultRedirectCode
+	}
+
+	// Only an unset delay is filled in, Shortlinks stored before RedirectAfter became optional may lack one
+	if shortlink.Spec.Code == 200 && shortlink.Spec.RedirectAfter == nil {
+		redirectAfter := int64(urlshortenerv1alpha1.DefaultRedirectAfter)
+		shortlink.Spec.RedirectAfter = &redirectAfter
+	}
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-urlshortener-cedi-dev-v1alpha1-shortlink,mutating=false,failurePolicy=fail,sideEffects=None,groups=urlshortener.cedi.dev,resources=shortlinks,verbs=create;update,versions=v1alpha1,name=vshortlink-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// ShortlinkCustomValidator struct is responsible for validating the Shortlink resource
+// when it is created, updated, or deleted.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type ShortlinkCustomValidator struct {
+	// client lists the other Shortlinks of the namespace to check the names and aliases are unique
+	client client.Reader
+
+	// rules compiles the expressions of the targeting rules, so invalid rules are rejected instead of skipped on every request
+	rules *rules.Evaluator
+
+	// policy restricts the targets of Shortlinks, if set
+	policy *policy.Policy
+}
+
+var _ webhook.CustomValidator = &ShortlinkCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type Shortlink.
+func (v *ShortlinkCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	shortlink, ok := obj.(*urlshortenerv1alpha1.Shortlink)
+	if !ok {
+		return nil, fmt.Errorf("expected a Shortlink object but got %T", obj)
+	}
+
+	otelzap.L().Ctx(ctx).Debug("Validation for Shortlink upon creation", zap.String("shortlink", shortlink.GetName()))
+
+	return nil, v.validateShortlink(ctx, shortlink)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type Shortlink.
+func (v *ShortlinkCustomValidator) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
+	shortlink, ok := newObj.(*urlshortenerv1alpha1.Shortlink)
+	if !ok {
+		return nil, fmt.Errorf("expected a Shortlink object for the newObj but got %T", newObj)
+	}
+
+	otelzap.L().Ctx(ctx).Debug("Validation for Shortlink upon update", zap.String("shortlink", shortlink.GetName()))
+
+	return nil, v.validateShortlink(ctx, shortlink)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type Shortlink.
+// Shortlinks can always be deleted.
+func (v *ShortlinkCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ShortlinkCustomValidator) validateShortlink(ctx context.Context, shortlink *urlshortenerv1alpha1.Shortlink) error {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+
+	if strings.TrimSpace(shortlink.Spec.Owner) == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("owner"), "every shortlink needs an owner"))
+	}
+
+	allErrs = append(allErrs, validateCode(specPath.Child("code"), shortlink.Spec.Code, false)...)
+	allErrs = append(allErrs, v.validateTarget(specPath.Child("target"), shortlink.Spec.Target)...)
+
+	if shortlink.Spec.NoArgsTarget != "" {
+		allErrs = append(allErrs, v.validateTarget(specPath.Child("noArgsTarget"), shortlink.Spec.NoArgsTarget)...)
+	}
+
+	if shortlink.Spec.FallbackTarget != "" {
+		allErrs = append(allErrs, v.validateTarget(specPath.Child("fallbackTarget"), shortlink.Spec.FallbackTarget)...)
+	}
+
+	for idx, entry := range shortlink.Spec.Schedule {
+		entryPath := specPath.Child("schedule").Index(idx)
+		allErrs = append(allErrs, v.validateTarget(entryPath.Child("target"), entry.Target)...)
+		allErrs = append(allErrs, validateCode(entryPath.Child("code"), entry.Code, true)...)
+	}
+
+	for idx, rule := range shortlink.Spec.Rules {
+		rulePath := specPath.Child("rules").Index(idx)
+		allErrs = append(allErrs, v.validateTarget(rulePath.Child("target"), rule.Target)...)
+		allErrs = append(allErrs, validateCode(rulePath.Child("code"), rule.Code, true)...)
+
+		if v.rules != nil {
+			if _, err := v.rules.Compile(rule.Expression); err != nil {
+				allErrs = append(allErrs, field.Invalid(rulePath.Child("expression"), rule.Expression, err.Error()))
+			}
+		}
+	}
+
+	totalWeight := 0
+	for idx, variant := range shortlink.Spec.Variants {
+		allErrs = append(allErrs, v.validateTarget(specPath.Child("variants").Index(idx).Child("target"), variant.Target)...)
+		totalWeight += variant.Weight
+	}
+
+	if len(shortlink.Spec.Variants) > 0 && totalWeight <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("variants"), totalWeight, "at least one variant needs a weight above 0"))
+	}
+
+	allErrs = append(allErrs, validateReservedNames(shortlink)...)
+
+	if len(allErrs) == 0 {
+		nameErrs, err := v.validateUniqueNames(ctx, shortlink)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+
+		allErrs = append(allErrs, nameErrs...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: urlshortenerv1alpha1.GroupVersion.Group, Kind: "Shortlink"},
+		shortlink.Name,
+		allErrs,
+	)
+}
+
+// validateCode checks the code is one of the declared redirect codes. Optional codes may be 0 to inherit the code of the Shortlink.
+func validateCode(path *field.Path, code int, optional bool) field.ErrorList {
+	if (optional && code == 0) || slices.Contains(urlshortenerv1alpha1.RedirectCodes, code) {
+		return nil
+	}
+
+	supported := make([]string, 0, len(urlshortenerv1alpha1.RedirectCodes))
+	for _, redirectCode := range urlshortenerv1alpha1.RedirectCodes {
+		supported = append(supported, fmt.Sprint(redirectCode))
+	}
+
+	return field.ErrorList{field.NotSupported(path, code, supported)}
+}
+
+// validateTarget checks the target is an http or https URL with a host that the policy allows. Targets without
+// scheme are redirected to via http, and placeholders of templated targets are only filled in on request.
+func (v *ShortlinkCustomValidator) validateTarget(path *field.Path, target string) field.ErrorList {
+	if strings.TrimSpace(target) == "" {
+		return field.ErrorList{field.Required(path, "a target is required")}
+	}
+
+	if strings.ContainsAny(target, " \t\r\n") {
+		return field.ErrorList{field.Invalid(path, target, "the target must not contain whitespace")}
+	}
+
+	templated := urlshortenerv1alpha1.TargetPlaceholder.ReplaceAllString(target, "placeholder")
+	if match := targetScheme.FindStringSubmatch(templated); match == nil || isPort(templated[len(match[0]):]) {
+		templated = "http://" + templated
+	}
+
+	parsed, err := url.Parse(templated)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, target, fmt.Sprintf("the target is not a valid URL: %v", err))}
+	}
+
+	if scheme := strings.ToLower(parsed.Scheme); scheme != "http" && scheme != "https" {
+		return field.ErrorList{field.Invalid(path, target, fmt.Sprintf("scheme %s is not supported, use http or https", parsed.Scheme))}
+	}
+
+	if parsed.Host == "" {
+		return field.ErrorList{field.Invalid(path, target, "the target has no host")}
+	}
+
+	if v.policy != nil {
+		if err := v.policy.CheckTarget(target); err != nil {
+			return field.ErrorList{field.Forbidden(path, fmt.Sprintf("the target is not allowed by policy: %v", err))}
+		}
+	}
+
+	return nil
+}
+
+// isPort returns true if s starts with the port of a host:port target such as localhost:8080/path
+func isPort(s string) bool {
+	digits := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			break
+		}
+
+		digits++
+	}
+
+	return digits > 0 && (digits == len(s) || s[digits] == '/' || s[digits] == '?' || s[digits] == '#')
+}
+
+// validateReservedNames checks no name, slug, alias or path of the Shortlink starts with a path segment the API server serves itself
+func validateReservedNames(shortlink *urlshortenerv1alpha1.Shortlink) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	check := func(path *field.Path, name string) {
+		segment, _, _ := strings.Cut(name, "/")
+		if slices.Contains(ReservedNames, strings.ToLower(segment)) {
+			allErrs = append(allErrs, field.Forbidden(path, fmt.Sprintf("%s is reserved, the reserved names are %s", segment, strings.Join(ReservedNames, ", "))))
+		}
+	}
+
+	check(field.NewPath("metadata", "name"), shortlink.Name)
+	check(field.NewPath("spec", "slug"), shortlink.Spec.Slug)
+	check(field.NewPath("spec", "path"), shortlink.Spec.Path)
+
+	for idx, alias := range shortlink.Spec.Aliases {
+		check(field.NewPath("spec", "aliases").Index(idx), alias)
+	}
+
+	return allErrs
+}
+
+// validateUniqueNames checks no name, slug, alias or path of the Shortlink is used by another Shortlink served under the same domain
+func (v *ShortlinkCustomValidator) validateUniqueNames(ctx context.Context, shortlink *urlshortenerv1alpha1.Shortlink) (field.ErrorList, error) {
+	if v.client == nil {
+		return nil, nil
+	}
+
+	shortlinks := &urlshortenerv1alpha1.ShortlinkList{}
+	if err := v.client.List(ctx, shortlinks, client.InNamespace(shortlink.Namespace)); err != nil {
+		return nil, fmt.Errorf("unable to list shortlinks: %w", err)
+	}
+
+	for idx := range shortlinks.Items {
+		other := &shortlinks.Items[idx]
+		if other.Name == shortlink.Name {
+			continue
+		}
+
+		if name := shortlink.ConflictingName(other); name != "" {
+			return field.ErrorList{field.Duplicate(field.NewPath("spec", "aliases"), fmt.Sprintf("%s is already in use by Shortlink %s", name, other.Name))}, nil
+		}
+	}
+
+	return nil, nil
+}
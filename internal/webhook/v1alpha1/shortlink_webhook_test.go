@@ -0,0 +1,238 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	urlshortenerv1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
+	"github.com/spechtlabs/urlshortener/pkg/policy"
+	"github.com/spechtlabs/urlshortener/pkg/rules"
+)
+
+var _ = Describe("Shortlink Webhook", func() {
+	var (
+		obj       *urlshortenerv1alpha1.Shortlink
+		oldObj    *urlshortenerv1alpha1.Shortlink
+		validator ShortlinkCustomValidator
+		defaulter ShortlinkCustomDefaulter
+	)
+
+	BeforeEach(func() {
+		obj = &urlshortenerv1alpha1.Shortlink{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "docs",
+				Namespace: "default",
+			},
+			Spec: urlshortenerv1alpha1.ShortlinkSpec{
+				Owner:  "owner@example.com",
+				Target: "https://example.com/docs",
+				Code:   301,
+			},
+		}
+		oldObj = obj.DeepCopy()
+		validator = ShortlinkCustomValidator{}
+		defaulter = ShortlinkCustomDefaulter{}
+	})
+
+	Context("When creating Shortlink under Defaulting Webhook", func() {
+		It("Should fill in the redirect code if it is unset", func() {
+			obj.Spec.Code = 0
+
+			By("calling the Default method to apply defaults")
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+
+			By("checking that the default values are set")
+			Expect(obj.Spec.Code).To(Equal(urlshortenerv1alpha1.DefaultRedirectCode))
+			Expect(obj.Spec.RedirectAfter).To(BeNil())
+		})
+
+		It("Should fill in the delay of HTML redirects", func() {
+			obj.Spec.Code = 200
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.RedirectAfter).To(HaveValue(Equal(int64(urlshortenerv1alpha1.DefaultRedirectAfter))))
+		})
+
+		It("Should keep values that are set", func() {
+			obj.Spec.Code = 200
+			redirectAfter := int64(10)
+			obj.Spec.RedirectAfter = &redirectAfter
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.Code).To(Equal(200))
+			Expect(obj.Spec.RedirectAfter).To(HaveValue(Equal(int64(10))))
+		})
+
+		It("Should keep HTML redirects without delay", func() {
+			obj.Spec.Code = 200
+			redirectAfter := int64(0)
+			obj.Spec.RedirectAfter = &redirectAfter
+
+			Expect(defaulter.Default(ctx, obj)).To(Succeed())
+			Expect(obj.Spec.RedirectAfter).To(HaveValue(BeZero()))
+		})
+	})
+
+	Context("When creating or updating Shortlink under Validating Webhook", func() {
+		It("Should admit a valid Shortlink", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should admit targets without scheme and templated targets", func() {
+			obj.Spec.Target = "example.com/docs"
+			obj.Spec.NoArgsTarget = "localhost:8080/docs"
+			obj.Spec.FallbackTarget = "https://example.com/{1}/{*}?q={q}"
+
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny a Shortlink without owner", func() {
+			obj.Spec.Owner = ""
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.owner")))
+		})
+
+		It("Should deny an undeclared redirect code", func() {
+			obj.Spec.Code = 404
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.code")))
+		})
+
+		It("Should deny targets with unsupported schemes", func() {
+			obj.Spec.Target = "javascript:alert(1)"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.target")))
+
+			obj.Spec.Target = "https://example.com"
+			obj.Spec.Rules = []urlshortenerv1alpha1.TargetRule{{Expression: "true", Target: "ftp://example.com"}}
+
+			_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[0].target")))
+		})
+
+		It("Should deny targets the policy does not allow", func() {
+			targetPolicy, err := policy.New(policy.Config{BlockedDomains: []string{"*.evil.example"}})
+			Expect(err).NotTo(HaveOccurred())
+			validator = ShortlinkCustomValidator{policy: targetPolicy}
+
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Target = "http://127.1/admin"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.target")))
+
+			obj.Spec.Target = "https://example.com"
+			obj.Spec.FallbackTarget = "https://{1}/"
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.fallbackTarget")))
+
+			obj.Spec.FallbackTarget = ""
+			obj.Spec.Variants = []urlshortenerv1alpha1.TargetVariant{{Name: "a", Target: "https://www.evil.example", Weight: 1}}
+			_, err = validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.variants[0].target")))
+		})
+
+		It("Should deny rules that do not compile", func() {
+			ruleEvaluator, err := rules.NewEvaluator()
+			Expect(err).NotTo(HaveOccurred())
+			validator = ShortlinkCustomValidator{rules: ruleEvaluator}
+
+			obj.Spec.Rules = []urlshortenerv1alpha1.TargetRule{
+				{Expression: `language == "de"`, Target: "https://example.com/de"},
+				{Expression: `langauge == "en"`, Target: "https://example.com/en"},
+				{Expression: `language`, Target: "https://example.com/other"},
+			}
+
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[1].expression")))
+			Expect(err).To(MatchError(ContainSubstring("spec.rules[2].expression")))
+			Expect(err).NotTo(MatchError(ContainSubstring("spec.rules[0].expression")))
+		})
+
+		It("Should deny variants that cannot receive any traffic", func() {
+			obj.Spec.Variants = []urlshortenerv1alpha1.TargetVariant{
+				{Name: "a", Target: "https://example.com/a", Weight: 0},
+				{Name: "b", Target: "https://example.com/b", Weight: 0},
+			}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("at least one variant needs a weight above 0")))
+
+			obj.Spec.Variants[1].Weight = 1
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny targets without host", func() {
+			obj.Spec.Target = "https:///docs"
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("has no host")))
+		})
+
+		It("Should deny reserved names", func() {
+			obj.Spec.Aliases = []string{"swagger"}
+
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.aliases[0]")))
+
+			obj.Spec.Aliases = nil
+			obj.Spec.Path = "api/docs"
+
+			_, err = validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("spec.path")))
+		})
+
+		It("Should always admit the deletion of a Shortlink", func() {
+			obj.Spec.Owner = ""
+
+			Expect(validator.ValidateDelete(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+
+	Context("When validating Shortlink against the other Shortlinks of its namespace", func() {
+		It("Should deny aliases that are already in use", func() {
+			existing := obj.DeepCopy()
+			existing.Name = "documentation"
+			existing.Spec.Aliases = []string{"manual"}
+			Expect(k8sClient.Create(ctx, existing)).To(Succeed())
+			DeferCleanup(func() {
+				Expect(k8sClient.Delete(ctx, existing)).To(Succeed())
+			})
+
+			validator = ShortlinkCustomValidator{client: k8sClient}
+
+			obj.Spec.Aliases = []string{"manual"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring("already in use by Shortlink documentation")))
+
+			By("allowing the same alias on another domain")
+			obj.Spec.Domains = []string{"go.example.com"}
+			existing.Spec.Domains = []string{"links.example.com"}
+			Expect(k8sClient.Update(ctx, existing)).To(Succeed())
+
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinCookieKeyLength is the minimum length of the key signing the cookies, so their signatures cannot be guessed
+const MinCookieKeyLength = 32
+
+const (
+	// sessionCookiePurpose signs the cookies of browser sessions
+	sessionCookiePurpose = "session"
+	// unlockCookiePurpose signs the cookies unlocking password-protected shortlinks
+	unlockCookiePurpose = "unlock"
+	// oauthStateCookiePurpose signs the cookies binding an OAuth login to the browser that started it
+	oauthStateCookiePurpose = "oauth-state"
+	// csrfTokenPurpose signs the tokens binding the forms of the server to the browser session they were rendered for
+	csrfTokenPurpose = "csrf"
+)
+
+// cookieSigner signs cookie values with an HMAC, so the server can trust cookies it issued itself
+type cookieSigner struct {
+	key []byte
+}
+
+// newCookieSigner creates a cookieSigner for cookies of the given purpose. Its key is derived from key and the purpose,
+// so a cookie issued for one purpose, e.g. unlocking a shortlink, cannot be replayed as a cookie of another, e.g. a session.
+func newCookieSigner(key []byte, purpose string) *cookieSigner {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("urlshortener cookie " + purpose))
+	return &cookieSigner{key: mac.Sum(nil)}
+}
+
+// Sign returns value together with its expiry time and signature, encoded as a cookie value
+func (c *cookieSigner) Sign(value string, expires time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value)) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(c.mac(payload))
+}
+
+// Verify returns the value of a cookie issued by Sign, and false if its signature is invalid or it expired
+func (c *cookieSigner) Verify(signed string, now time.Time) (string, bool) {
+	idx := strings.LastIndex(signed, ".")
+	if idx < 0 {
+		return "", false
+	}
+
+	payload := signed[:idx]
+	signature, err := base64.RawURLEncoding.DecodeString(signed[idx+1:])
+	if err != nil || !hmac.Equal(signature, c.mac(payload)) {
+		return "", false
+	}
+
+	encodedValue, expiry, found := strings.Cut(payload, ".")
+	if !found {
+		return "", false
+	}
+
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return "", false
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encodedValue)
+	if err != nil {
+		return "", false
+	}
+
+	return string(value), true
+}
+
+func (c *cookieSigner) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCookieSigner(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	signer := newCookieSigner([]byte("0123456789abcdef0123456789abcdef"), unlockCookiePurpose)
+	signed := signer.Sign("some-uid", now.Add(time.Minute))
+
+	tests := []struct {
+		name   string
+		signer *cookieSigner
+		signed string
+		now    time.Time
+		want   string
+		wantOk bool
+	}{
+		{
+			name:   "valid cookie",
+			signer: signer,
+			signed: signed,
+			now:    now,
+			want:   "some-uid",
+			wantOk: true,
+		},
+		{
+			name:   "expired cookie",
+			signer: signer,
+			signed: signed,
+			now:    now.Add(time.Minute),
+		},
+		{
+			name:   "tampered value",
+			signer: signer,
+			signed: base64.RawURLEncoding.EncodeToString([]byte("other-uid")) + signed[strings.Index(signed, "."):],
+			now:    now,
+		},
+		{
+			name:   "tampered expiry",
+			signer: signer,
+			signed: strings.Replace(signed, ".1700000060.", ".1900000000.", 1),
+			now:    now.Add(time.Hour),
+		},
+		{
+			name:   "tampered signature",
+			signer: signer,
+			signed: signed[:len(signed)-2] + "AA",
+			now:    now,
+		},
+		{
+			name:   "signed with another key",
+			signer: newCookieSigner([]byte("fedcba9876543210fedcba9876543210"), unlockCookiePurpose),
+			signed: signed,
+			now:    now,
+		},
+		{
+			name:   "signed for another purpose",
+			signer: newCookieSigner([]byte("0123456789abcdef0123456789abcdef"), sessionCookiePurpose),
+			signed: signed,
+			now:    now,
+		},
+		{
+			name:   "not a signed cookie",
+			signer: signer,
+			signed: "some-uid",
+			now:    now,
+		},
+		{
+			name:   "empty cookie",
+			signer: signer,
+			signed: "",
+			now:    now,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.signer.Verify(tt.signed, tt.now)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("Verify() = (%q, %v), want (%q, %v)", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sierrasoftworks/humane-errors-go"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// maxSlugAttempts is the number of generated slugs tried before giving up on creating a shortlink
+const maxSlugAttempts = 10
+
+// errNoFreeSlug is returned if every generated slug was taken already
+var errNoFreeSlug = humane.New("Unable to generate a free slug",
+	"retry the request, or increase the slug length of the server if this happens often",
+)
+
+// HandleCreateRandomShortLink handles the creation of a shortlink with a slug generated by the server
+// @BasePath /api/v1/
+// @Summary       create new shortlink with a generated slug
+// @Schemes       http https
+// @Description   create a new shortlink with a collision-free slug generated by the server
+// @Accept        application/json
+// @Produce       application/json
+// @Param         spec        body      v1alpha1.ShortlinkSpec true   "shortlink spec"
+// @Success       200         {object}  v1alpha1.ShortLinkAPI  "Success"
+// @Failure       400         {object}  int                    "BadRequest"
+// @Failure       401         {object}  int                    "Unauthorized"
+// @Failure       409         {object}  int                    "Conflict"
+// @Failure       500         {object}  int                    "InternalServerError"
+// @Tags api/v1/
+// @Router /api/v1/shortlink/ [post]
+// @Security bearerAuth
+func (s *UrlshortenerServer) HandleCreateRandomShortLink(ct *gin.Context) {
+	user := apiUser(ct)
+
+	ctx := ct.Request.Context()
+	span := trace.SpanFromContext(ctx)
+
+	span.SetAttributes(attribute.String("referrer", ct.Request.Referer()))
+
+	if len(user.Login) == 0 {
+		err := humane.New("No user found for request",
+			"ensure you include a Bearer token in the Authorization header, e.g. Authorization: Bearer <token> or Authorization: token <token>",
+		)
+
+		otelzap.L().WithError(err).Ctx(ctx).Error(err.Error(),
+			zap.String("operation", "create"),
+		)
+
+		ct.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "advice": err.Advice()})
+		return
+	}
+
+	jsonData, err := io.ReadAll(ct.Request.Body)
+	if err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to read request-body",
+			zap.String("operation", "create"),
+		)
+		ct.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec := v1alpha1.ShortlinkSpec{}
+	if err := json.Unmarshal(jsonData, &spec); err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to read spec-json",
+			zap.String("operation", "create"),
+		)
+		ct.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if spec.Target == "" {
+		ct.JSON(http.StatusBadRequest, gin.H{"error": "A target is required to generate a slug"})
+		return
+	}
+
+	// The name of the shortlink is generated, so a name or path in the spec would contradict it
+	spec.Slug = ""
+	spec.Path = ""
+
+	shortlink, shortlinkName, attempts, err := createWithGeneratedSlug(spec, s.slugs.Generate, func(shortlink *v1alpha1.Shortlink) error {
+		return s.userClient.Create(ctx, user, shortlink)
+	})
+	if err == errNoFreeSlug {
+		otelzap.L().WithError(errNoFreeSlug).Ctx(ctx).Error(errNoFreeSlug.Error(),
+			zap.Int("attempts", attempts),
+			zap.String("operation", "create"),
+		)
+
+		ct.JSON(http.StatusConflict, gin.H{"error": errNoFreeSlug.Error(), "advice": errNoFreeSlug.Advice()})
+		return
+	}
+
+	if err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to create ShortLink",
+			zap.Int("attempts", attempts),
+			zap.String("operation", "create"),
+		)
+
+		ct.JSON(createErrorStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	span.SetAttributes(attribute.String("shortlink", shortlinkName), attribute.Int("attempts", attempts))
+
+	ct.JSON(http.StatusOK, v1alpha1.ShortLinkAPI{
+		Name:   shortlink.Name,
+		URL:    shortlinkURL(ct, shortlinkName),
+		Spec:   shortlink.Spec,
+		Status: shortlink.Status,
+	})
+}
+
+// createWithGeneratedSlug creates a shortlink from spec under the first slug returned by generate that is not taken yet.
+// It returns the created shortlink, its slug and the number of attempts, or errNoFreeSlug if all maxSlugAttempts slugs were taken.
+func createWithGeneratedSlug(spec v1alpha1.ShortlinkSpec, generate func(target string, attempt int) (string, error), create func(*v1alpha1.Shortlink) error) (*v1alpha1.Shortlink, string, int, error) {
+	for attempt := range maxSlugAttempts {
+		shortlinkName, err := generate(spec.Target, attempt)
+		if err != nil {
+			return nil, "", attempt + 1, err
+		}
+
+		shortlink := &v1alpha1.Shortlink{
+			Spec: *spec.DeepCopy(),
+		}
+		nameShortlink(shortlink, shortlinkName)
+
+		err = create(shortlink)
+		if err == nil {
+			return shortlink, shortlinkName, attempt + 1, nil
+		}
+
+		// The generated slug is taken, try the next one
+		if !isSlugTaken(err) {
+			return nil, "", attempt + 1, err
+		}
+	}
+
+	return nil, "", maxSlugAttempts, errNoFreeSlug
+}
+
+// isSlugTaken returns true if a shortlink could not be created because its name is used by another shortlink already
+func isSlugTaken(err error) bool {
+	return apierrors.IsAlreadyExists(err) || strings.Contains(err.Error(), "already in use")
+}
+
+// createErrorStatusCode maps an error creating a shortlink to the HTTP status code of the response
+func createErrorStatusCode(err error) int {
+	switch {
+	case isSlugTaken(err):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "not allowed by policy") || strings.Contains(err.Error(), "is not a valid rule"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
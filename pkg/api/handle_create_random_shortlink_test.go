@@ -0,0 +1,130 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+	shortlinkClient "github.com/spechtlabs/urlshortener/pkg/client"
+)
+
+func TestCreateWithGeneratedSlug(t *testing.T) {
+	spec := v1alpha1.ShortlinkSpec{Target: "https://example.com"}
+	generate := func(target string, attempt int) (string, error) {
+		return fmt.Sprintf("slug%d", attempt), nil
+	}
+
+	nameTaken := shortlinkClient.NewNameConflictError("slug0", "other")
+	objectTaken := apierrors.NewAlreadyExists(schema.GroupResource{Group: "urlshortener.cedi.dev", Resource: "shortlinks"}, "slug1")
+	policyViolation := shortlinkClient.NewPolicyViolationError("https://example.com", errors.New("host is not allowed"))
+
+	tests := []struct {
+		name         string
+		createErrors []error
+		wantName     string
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name:         "first slug is free",
+			wantName:     "slug0",
+			wantAttempts: 1,
+		},
+		{
+			name:         "slug used as a name or alias is retried",
+			createErrors: []error{nameTaken},
+			wantName:     "slug1",
+			wantAttempts: 2,
+		},
+		{
+			name:         "slug used as an object name is retried",
+			createErrors: []error{nameTaken, objectTaken},
+			wantName:     "slug2",
+			wantAttempts: 3,
+		},
+		{
+			name:         "other errors are not retried",
+			createErrors: []error{nameTaken, policyViolation},
+			wantAttempts: 2,
+			wantErr:      policyViolation,
+		},
+		{
+			name:         "gives up once all attempts are taken",
+			createErrors: []error{nameTaken, nameTaken, nameTaken, nameTaken, nameTaken, nameTaken, nameTaken, nameTaken, nameTaken, nameTaken},
+			wantAttempts: maxSlugAttempts,
+			wantErr:      errNoFreeSlug,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created []string
+			create := func(shortlink *v1alpha1.Shortlink) error {
+				created = append(created, shortlink.Name)
+				if len(created) <= len(tt.createErrors) {
+					return tt.createErrors[len(created)-1]
+				}
+				return nil
+			}
+
+			shortlink, name, attempts, err := createWithGeneratedSlug(spec, generate, create)
+			if err != tt.wantErr {
+				t.Fatalf("createWithGeneratedSlug() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if attempts != tt.wantAttempts || len(created) != tt.wantAttempts {
+				t.Errorf("createWithGeneratedSlug() took %d attempts and %d creates, want %d", attempts, len(created), tt.wantAttempts)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if name != tt.wantName || shortlink.Name != tt.wantName || shortlink.Spec.Target != spec.Target {
+				t.Errorf("createWithGeneratedSlug() = %s named %s, want %s", shortlink.Name, name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestCreateWithGeneratedSlugStopsOnGeneratorErrors(t *testing.T) {
+	generateErr := errors.New("no randomness")
+	generate := func(target string, attempt int) (string, error) {
+		return "", generateErr
+	}
+	create := func(shortlink *v1alpha1.Shortlink) error {
+		t.Fatalf("create called for %s", shortlink.Name)
+		return nil
+	}
+
+	if _, _, _, err := createWithGeneratedSlug(v1alpha1.ShortlinkSpec{Target: "https://example.com"}, generate, create); err != generateErr {
+		t.Errorf("createWithGeneratedSlug() error = %v, want %v", err, generateErr)
+	}
+}
+
+func TestCreateErrorStatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "name in use", err: shortlinkClient.NewNameConflictError("docs", "handbook"), want: http.StatusConflict},
+		{name: "object exists", err: apierrors.NewAlreadyExists(schema.GroupResource{Resource: "shortlinks"}, "docs"), want: http.StatusConflict},
+		{name: "policy violation", err: shortlinkClient.NewPolicyViolationError("javascript:alert(1)", errors.New("scheme is blocked")), want: http.StatusBadRequest},
+		{name: "invalid rule", err: shortlinkClient.NewInvalidRuleError("language ==", errors.New("syntax error")), want: http.StatusBadRequest},
+		{name: "other error", err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := createErrorStatusCode(tt.err); got != tt.want {
+				t.Errorf("createErrorStatusCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
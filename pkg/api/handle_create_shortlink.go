@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sierrasoftworks/humane-errors-go"
@@ -26,29 +27,29 @@ import (
 // @Accept        application/json
 // @Produce       text/plain
 // @Produce       application/json
-// @Param         shortlink   path      string                 	false  					"the shortlink URL part (shortlink id)" example(home)
-// @Param         spec        body      v1alpha1.ShortLinkSpec 	true   					"shortlink spec"
-// @Success       200         {object}  int     				"Success"
-// @Success       301         {object}  int     				"MovedPermanently"
-// @Success       302         {object}  int     				"Found"
-// @Success       307         {object}  int     				"TemporaryRedirect"
-// @Success       308         {object}  int     				"PermanentRedirect"
-// @Failure       401         {object}  int                     "Unauthorized"
-// @Failure       404         {object}  int     				"NotFound"
-// @Failure       500         {object}  int     				"InternalServerError"
+// @Param         shortlink   path      string                 true   "the shortlink URL part (shortlink id)" example(home)
+// @Param         path        path      string                 false  "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook"
+// @Param         spec        body      v1alpha1.ShortlinkSpec true   "shortlink spec"
+// @Success       200         {object}  v1alpha1.ShortLinkAPI  "Success"
+// @Failure       400         {object}  int                    "BadRequest"
+// @Failure       401         {object}  int                    "Unauthorized"
+// @Failure       404         {object}  int                    "NotFound"
+// @Failure       409         {object}  int                    "Conflict"
+// @Failure       500         {object}  int                    "InternalServerError"
 // @Tags api/v1/
 // @Router /api/v1/shortlink/{shortlink} [post]
+// @Router /api/v1/shortlink/{shortlink}/{path} [post]
 // @Security bearerAuth
 func (s *UrlshortenerServer) HandleCreateShortLink(ct *gin.Context) {
-	shortlinkName := ct.Param("shortlink")
-	userName := ct.GetString("githubUserName")
+	shortlinkName := apiShortlinkParam(ct)
+	user := apiUser(ct)
 
 	ctx := ct.Request.Context()
 	span := trace.SpanFromContext(ctx)
 
 	span.SetAttributes(attribute.String("shortlink", shortlinkName), attribute.String("referrer", ct.Request.Referer()))
 
-	if len(userName) == 0 {
+	if len(user.Login) == 0 {
 		err := humane.New("No user found for request",
 			"ensure you include a Bearer token in the Authorization header, e.g. Authorization: Bearer <token> or Authorization: token <token>",
 		)
@@ -88,17 +89,27 @@ func (s *UrlshortenerServer) HandleCreateShortLink(ct *gin.Context) {
 		return
 	}
 
-	if err := s.userClient.Create(ctx, userName, &shortlink); err != nil {
+	nameShortlink(&shortlink, shortlinkName)
+
+	if err := s.userClient.Create(ctx, user, &shortlink); err != nil {
 		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to create ShortLink",
 			zap.String("shortlink", shortlinkName),
 			zap.String("operation", "create"),
 		)
-		ct.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "already in use") {
+			statusCode = http.StatusConflict
+		} else if strings.Contains(err.Error(), "not allowed by policy") || strings.Contains(err.Error(), "is not a valid rule") {
+			statusCode = http.StatusBadRequest
+		}
+
+		ct.JSON(statusCode, gin.H{"error": err.Error()})
 		return
 	}
 
 	ct.JSON(http.StatusOK, v1alpha1.ShortLinkAPI{
 		Name:   shortlink.Name,
+		URL:    shortlinkURL(ct, shortlinkName),
 		Spec:   shortlink.Spec,
 		Status: shortlink.Status,
 	})
@@ -2,7 +2,6 @@ package api
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sierrasoftworks/humane-errors-go"
@@ -22,24 +21,28 @@ import (
 // @Description   get a shortlink
 // @Produce       text/plain
 // @Produce       application/json
-// @Param         shortlink   path      string    false          "the shortlink URL part (shortlink id)" example(home)
-// @Success       200         {object}  ShortLink "Success"
-// @Failure       401         {object}  int       "Unauthorized"
-// @Failure       404         {object}  int       "NotFound"
-// @Failure       500         {object}  int       "InternalServerError"
+// @Param         shortlink   path      string                true   "the shortlink URL part (shortlink id)" example(home)
+// @Param         path        path      string                false  "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook"
+// @Param         domain      query     string                false  "the domain the shortlink is served under, if the name is used under several domains"
+// @Success       200         {object}  v1alpha1.ShortLinkAPI "Success"
+// @Failure       401         {object}  int                   "Unauthorized"
+// @Failure       404         {object}  int                   "NotFound"
+// @Failure       409         {object}  int                   "Conflict"
+// @Failure       500         {object}  int                   "InternalServerError"
 // @Tags api/v1/
 // @Router /api/v1/shortlink/{shortlink} [get]
+// @Router /api/v1/shortlink/{shortlink}/{path} [get]
 // @Security bearerAuth
 func (s *UrlshortenerServer) HandleGetShortLink(ct *gin.Context) {
-	shortlinkName := ct.Param("shortlink")
-	userName := ct.GetString("githubUserName")
+	shortlinkName := apiShortlinkParam(ct)
+	user := apiUser(ct)
 
 	ctx := ct.Request.Context()
 	span := trace.SpanFromContext(ctx)
 
 	span.SetAttributes(attribute.String("shortlink", shortlinkName), attribute.String("referrer", ct.Request.Referer()))
 
-	if len(userName) == 0 {
+	if len(user.Login) == 0 {
 		err := humane.New("No user found for request",
 			"ensure you include a Bearer token in the Authorization header, e.g. Authorization: Bearer <token> or Authorization: token <token>",
 		)
@@ -53,12 +56,9 @@ func (s *UrlshortenerServer) HandleGetShortLink(ct *gin.Context) {
 		return
 	}
 
-	shortlink, err := s.userClient.Get(ctx, userName, shortlinkName)
+	shortlink, err := s.getUserShortlink(ctx, user, shortlinkName, apiShortlinkDomain(ct))
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "not found") {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := lookupErrorStatusCode(err)
 
 		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to get ShortLink",
 			zap.String("shortlink", shortlinkName),
@@ -22,23 +22,24 @@ import (
 // @Description   list shortlinks
 // @Produce       text/plain
 // @Produce       application/json
-// @Success       200         {object} []ShortLink "Success"
-// @Failure       401         {object} int         "Unauthorized"
-// @Failure       404         {object} int         "NotFound"
-// @Failure       500         {object} int         "InternalServerError"
+// @Param         domain      query    string                  false "only list the shortlinks served under this domain"
+// @Success       200         {array}  v1alpha1.ShortLinkAPI   "Success"
+// @Failure       401         {object} int                     "Unauthorized"
+// @Failure       404         {object} int                     "NotFound"
+// @Failure       500         {object} int                     "InternalServerError"
 // @Tags api/v1/
 // @Router /api/v1/shortlink/ [get]
 // @Security bearerAuth
 func (s *UrlshortenerServer) HandleListShortLink(ct *gin.Context) {
 	shortlinkName := ct.Param("shortlink")
-	userName := ct.GetString("githubUserName")
+	user := apiUser(ct)
 
 	ctx := ct.Request.Context()
 	span := trace.SpanFromContext(ctx)
 
 	span.SetAttributes(attribute.String("shortlink", shortlinkName), attribute.String("referrer", ct.Request.Referer()))
 
-	if len(userName) == 0 {
+	if len(user.Login) == 0 {
 		err := humane.New("No user found for request",
 			"ensure you include a Bearer token in the Authorization header, e.g. Authorization: Bearer <token> or Authorization: token <token>",
 		)
@@ -52,7 +53,7 @@ func (s *UrlshortenerServer) HandleListShortLink(ct *gin.Context) {
 		return
 	}
 
-	shortlinkList, err := s.userClient.List(ctx, userName)
+	shortlinkList, err := s.userClient.List(ctx, user)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if strings.Contains(err.Error(), "not found") {
@@ -64,14 +65,19 @@ func (s *UrlshortenerServer) HandleListShortLink(ct *gin.Context) {
 		return
 	}
 
-	targetList := make([]v1alpha1.ShortLinkAPI, len(shortlinkList.Items))
+	domain, filterDomain := ct.GetQuery("domain")
+	targetList := make([]v1alpha1.ShortLinkAPI, 0, len(shortlinkList.Items))
 
-	for idx, shortlink := range shortlinkList.Items {
-		targetList[idx] = v1alpha1.ShortLinkAPI{
+	for _, shortlink := range shortlinkList.Items {
+		if filterDomain && !shortlink.ServesDomain(domain) {
+			continue
+		}
+
+		targetList = append(targetList, v1alpha1.ShortLinkAPI{
 			Name:   shortlink.Name,
 			Spec:   shortlink.Spec,
 			Status: shortlink.Status,
-		}
+		})
 	}
 
 	ct.JSON(http.StatusOK, targetList)
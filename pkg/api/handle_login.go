@@ -0,0 +1,250 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/oauth2"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+	"github.com/spechtlabs/urlshortener/pkg/api/middleware"
+	shortlinkClient "github.com/spechtlabs/urlshortener/pkg/client"
+)
+
+const (
+	// sessionCookieName is the name of the cookie holding the GitHub user of a browser session
+	sessionCookieName = "urlshortener_session"
+	// sessionMaxAge is how long a browser session lasts before the user has to sign in again
+	sessionMaxAge = 8 * time.Hour
+
+	// oauthStateCookieName is the name of the cookie binding an OAuth login to the browser that started it
+	oauthStateCookieName = "urlshortener_oauth_state"
+	// oauthStateMaxAge is how long a user has to complete the GitHub login
+	oauthStateMaxAge = 10 * time.Minute
+)
+
+// sessionUser returns the GitHub user of the browser session, with an empty login if there is none
+func (s *UrlshortenerServer) sessionUser(ct *gin.Context) shortlinkClient.User {
+	cookie, err := ct.Cookie(sessionCookieName)
+	if err != nil {
+		return shortlinkClient.User{}
+	}
+
+	session, ok := s.sessionCookies.Verify(cookie, time.Now())
+	if !ok {
+		return shortlinkClient.User{}
+	}
+
+	// Logins cannot contain spaces, so the display name following the first one may contain them.
+	// Sessions started before the display name was stored only hold the login.
+	login, name, _ := strings.Cut(session, " ")
+	return shortlinkClient.User{Login: login, Name: name}
+}
+
+// apiUser returns the GitHub user the GitHubUserAuthMiddleware authenticated the API request with,
+// with an empty login if there is none
+func apiUser(ct *gin.Context) shortlinkClient.User {
+	return shortlinkClient.User{
+		Login: ct.GetString("githubUserName"),
+		Name:  ct.GetString("githubDisplayName"),
+	}
+}
+
+// authorizeViewer checks the visibility of a shortlink against the browser session of the request.
+// Anonymous visitors of a non-public shortlink are sent to the GitHub login, signed-in visitors that
+// may not see the shortlink get the same 404 page as for a shortlink that does not exist.
+// Returns false if the response has already been written.
+func (s *UrlshortenerServer) authorizeViewer(ct *gin.Context, shortlink *v1alpha1.Shortlink) bool {
+	if shortlink.Spec.Visibility == "" || shortlink.Spec.Visibility == v1alpha1.ShortlinkVisibilityPublic {
+		return true
+	}
+
+	ct.Header("Cache-Control", "no-store")
+
+	user := s.sessionUser(ct)
+	if shortlink.IsVisibleTo(user.Login, user.Name) {
+		return true
+	}
+
+	if user.Login == "" && s.oauth != nil {
+		ct.Redirect(http.StatusFound, "/_/login?redirect="+url.QueryEscape(ct.Request.URL.RequestURI()))
+		return false
+	}
+
+	ct.HTML(http.StatusNotFound, "404.html", gin.H{})
+	return false
+}
+
+// HandleLogin starts the GitHub login of a browser session
+// @BasePath /
+// @Summary       sign in with GitHub
+// @Schemes       http https
+// @Description   redirect to GitHub to sign in and come back to the given path afterwards
+// @Param         redirect    query     string  false "path to return to after signing in"
+// @Success       302         {object}  int     "Found"
+// @Failure       404         {object}  int     "NotFound"
+// @Failure       500         {object}  int     "InternalServerError"
+// @Tags default
+// @Router /_/login [get]
+func (s *UrlshortenerServer) HandleLogin(ct *gin.Context) {
+	ctx := ct.Request.Context()
+
+	if s.oauth == nil {
+		ct.HTML(http.StatusNotFound, "404.html", gin.H{})
+		return
+	}
+
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to generate OAuth state",
+			zap.String("operation", "login"),
+		)
+
+		ct.HTML(http.StatusInternalServerError, "500.html", gin.H{})
+		return
+	}
+
+	stateValue := base64.RawURLEncoding.EncodeToString(state)
+	redirect := localRedirect(ct.Query("redirect"))
+
+	ct.Header("Cache-Control", "no-store")
+	s.setCookie(ct, oauthStateCookieName, s.oauthStateCookies.Sign(stateValue+" "+redirect, time.Now().Add(oauthStateMaxAge)), oauthStateMaxAge, "/_/")
+
+	ct.Redirect(http.StatusFound, s.oauthConfig(ct).AuthCodeURL(stateValue))
+}
+
+// HandleLoginCallback completes the GitHub login of a browser session
+// @BasePath /
+// @Summary       GitHub login callback
+// @Schemes       http https
+// @Description   exchange the GitHub authorization code for a browser session
+// @Param         code        query     string  true  "authorization code"
+// @Param         state       query     string  true  "OAuth state"
+// @Success       302         {object}  int     "Found"
+// @Failure       401         {object}  int     "Unauthorized"
+// @Failure       404         {object}  int     "NotFound"
+// @Failure       500         {object}  int     "InternalServerError"
+// @Tags default
+// @Router /_/callback [get]
+func (s *UrlshortenerServer) HandleLoginCallback(ct *gin.Context) {
+	ctx := ct.Request.Context()
+	span := trace.SpanFromContext(ctx)
+
+	if s.oauth == nil {
+		ct.HTML(http.StatusNotFound, "404.html", gin.H{})
+		return
+	}
+
+	ct.Header("Cache-Control", "no-store")
+
+	stateCookie, err := ct.Cookie(oauthStateCookieName)
+	if err != nil {
+		ct.HTML(http.StatusUnauthorized, "404.html", gin.H{})
+		return
+	}
+
+	// The state cookie is single use
+	s.setCookie(ct, oauthStateCookieName, "", -time.Second, "/_/")
+
+	stateRedirect, ok := s.oauthStateCookies.Verify(stateCookie, time.Now())
+	state, redirect, found := strings.Cut(stateRedirect, " ")
+	if !ok || !found || state != ct.Query("state") {
+		otelzap.L().Ctx(ctx).Warn("OAuth state mismatch",
+			zap.String("operation", "login"),
+		)
+
+		ct.HTML(http.StatusUnauthorized, "404.html", gin.H{})
+		return
+	}
+
+	token, err := s.oauthConfig(ct).Exchange(ctx, ct.Query("code"))
+	if err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to exchange OAuth code",
+			zap.String("operation", "login"),
+		)
+
+		ct.HTML(http.StatusInternalServerError, "500.html", gin.H{})
+		return
+	}
+
+	user, err := middleware.GetGitHubUserInfo(ctx, token.AccessToken)
+	if err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to get GitHub user",
+			zap.String("operation", "login"),
+		)
+
+		ct.HTML(http.StatusInternalServerError, "500.html", gin.H{})
+		return
+	}
+
+	span.SetAttributes(attribute.String("user", user.Login))
+
+	s.setCookie(ct, sessionCookieName, s.sessionCookies.Sign(user.Login+" "+user.Name, time.Now().Add(sessionMaxAge)), sessionMaxAge, "/")
+	ct.Redirect(http.StatusFound, redirect)
+}
+
+// HandleLogout ends the browser session
+// @BasePath /
+// @Summary       sign out
+// @Schemes       http https
+// @Description   end the browser session and return to the given path
+// @Param         redirect    query     string  false "path to return to after signing out"
+// @Success       302         {object}  int     "Found"
+// @Tags default
+// @Router /_/logout [get]
+func (s *UrlshortenerServer) HandleLogout(ct *gin.Context) {
+	ct.Header("Cache-Control", "no-store")
+
+	s.setCookie(ct, sessionCookieName, "", -time.Second, "/")
+	ct.Redirect(http.StatusFound, localRedirect(ct.Query("redirect")))
+}
+
+// oauthConfig returns the OAuth configuration for a request. Without a configured redirect URL,
+// GitHub sends the user back to the host the login was started on.
+func (s *UrlshortenerServer) oauthConfig(ct *gin.Context) *oauth2.Config {
+	if s.oauth.RedirectURL != "" {
+		return s.oauth
+	}
+
+	config := *s.oauth
+	config.RedirectURL = requestBaseURL(ct) + "/_/callback"
+	return &config
+}
+
+// requestBaseURL returns the scheme and host the request was sent to, e.g. https://go.example.com
+func requestBaseURL(ct *gin.Context) string {
+	scheme := "http"
+	if ct.Request.TLS != nil || ct.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return scheme + "://" + ct.Request.Host
+}
+
+// setCookie sets an HTTP-only cookie that is only sent over TLS when the request came in over TLS
+func (s *UrlshortenerServer) setCookie(ct *gin.Context, name string, value string, maxAge time.Duration, path string) {
+	secure := ct.Request.TLS != nil || ct.GetHeader("X-Forwarded-Proto") == "https"
+
+	// Lax, so the cookies are sent along when GitHub redirects back to us
+	ct.SetSameSite(http.SameSiteLaxMode)
+	ct.SetCookie(name, value, int(maxAge.Seconds()), path, "", secure, true)
+}
+
+// localRedirect only allows redirects to paths on this server, so the login cannot be abused as an open redirect
+func localRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") {
+		return "/"
+	}
+
+	return redirect
+}
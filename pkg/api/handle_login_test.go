@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+func TestSessionUserOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s := &UrlshortenerServer{sessionCookies: newCookieSigner([]byte("0123456789abcdef0123456789abcdef"), sessionCookiePurpose)}
+
+	legacy := &v1alpha1.Shortlink{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy"},
+		Spec:       v1alpha1.ShortlinkSpec{Owner: "Jane Doe", Visibility: v1alpha1.ShortlinkVisibilityOwners},
+	}
+	byLogin := &v1alpha1.Shortlink{
+		ObjectMeta: metav1.ObjectMeta{Name: "by-login", Labels: map[string]string{v1alpha1.OwnedByLoginLabel: "true"}},
+		Spec:       v1alpha1.ShortlinkSpec{Owner: "jdoe", Visibility: v1alpha1.ShortlinkVisibilityOwners},
+	}
+
+	tests := []struct {
+		name      string
+		session   string
+		shortlink *v1alpha1.Shortlink
+		want      bool
+	}{
+		{name: "legacy shortlink owned by the display name", session: "jdoe Jane Doe", shortlink: legacy, want: true},
+		{name: "legacy shortlink in a session without display name", session: "jdoe", shortlink: legacy, want: false},
+		{name: "shortlink owned by the login", session: "jdoe Jane Doe", shortlink: byLogin, want: true},
+		{name: "display name impersonating a login", session: "mallory jdoe", shortlink: byLogin, want: false},
+		{name: "other user", session: "mallory Mallory", shortlink: legacy, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ct.Request = httptest.NewRequest("GET", "/"+tt.shortlink.Name, nil)
+			ct.Request.AddCookie(&http.Cookie{Name: sessionCookieName, Value: s.sessionCookies.Sign(tt.session, time.Now().Add(time.Hour))})
+
+			user := s.sessionUser(ct)
+			if got := tt.shortlink.IsVisibleTo(user.Login, user.Name); got != tt.want {
+				t.Errorf("IsVisibleTo(%q, %q) = %v, want %v", user.Login, user.Name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// maxSuggestions is the number of similar shortlinks offered on the 404 page
+const maxSuggestions = 5
+
+// renderNotFound renders the 404 page with the given status code for a shortlink that does not exist.
+// It suggests similar shortlinks the visitor may see and offers signed-in visitors to create the missing shortlink.
+func (s *UrlshortenerServer) renderNotFound(ct *gin.Context, statusCode int, shortlinkName string, data gin.H) {
+	ctx := ct.Request.Context()
+	user := s.sessionUser(ct)
+
+	if data == nil {
+		data = gin.H{}
+	}
+
+	data["shortlink"] = shortlinkName
+	data["userName"] = user.Login
+	data["suggestions"] = s.resolver.Suggest(ctx, shortlinkName, maxSuggestions, func(shortlink *v1alpha1.Shortlink) bool {
+		return shortlink.IsVisibleTo(user.Login, user.Name) && shortlink.ServesDomain(ct.Request.Host)
+	})
+
+	if user.Login != "" {
+		data["csrfToken"] = s.csrfToken(ct)
+	}
+
+	if user.Login == "" && s.oauth != nil {
+		data["loginURL"] = "/_/login?redirect=" + url.QueryEscape("/"+shortlinkName)
+	}
+
+	// The page depends on the session of the visitor, and the link may be created any moment
+	ct.Header("Cache-Control", "no-store")
+
+	ct.HTML(statusCode, "404.html", data)
+}
+
+// csrfToken returns the token the forms of the server send along, so other sites cannot submit them in the name of a
+// signed-in visitor. It is bound to the session cookie, so it is only valid for the session it was rendered for.
+func (s *UrlshortenerServer) csrfToken(ct *gin.Context) string {
+	session, err := ct.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+
+	return s.csrfTokens.Sign(session, time.Now().Add(sessionMaxAge))
+}
+
+// isValidCSRFToken returns true if token was issued by csrfToken for the session of the request
+func (s *UrlshortenerServer) isValidCSRFToken(ct *gin.Context, token string) bool {
+	session, err := ct.Cookie(sessionCookieName)
+	if err != nil || session == "" {
+		return false
+	}
+
+	tokenSession, ok := s.csrfTokens.Verify(token, time.Now())
+	return ok && tokenSession == session
+}
+
+// HandleCreateShortLinkForm creates a shortlink from the form on the 404 page
+// @BasePath /
+// @Summary       create a shortlink from the 404 page
+// @Schemes       http https
+// @Description   create a missing shortlink with the signed-in user as owner
+// @Accept        application/x-www-form-urlencoded
+// @Produce       text/html
+// @Param         name        formData  string  true  "shortlink id"
+// @Param         target      formData  string  true  "target of the shortlink"
+// @Param         csrfToken   formData  string  true  "token of the form, bound to the browser session"
+// @Success       303         {object}  int     "SeeOther"
+// @Failure       400         {object}  int     "BadRequest"
+// @Failure       401         {object}  int     "Unauthorized"
+// @Failure       403         {object}  int     "Forbidden"
+// @Failure       409         {object}  int     "Conflict"
+// @Failure       500         {object}  int     "InternalServerError"
+// @Tags default
+// @Router /_/create [post]
+func (s *UrlshortenerServer) HandleCreateShortLinkForm(ct *gin.Context) {
+	shortlinkName := strings.TrimSpace(ct.PostForm("name"))
+	target := strings.TrimSpace(ct.PostForm("target"))
+	user := s.sessionUser(ct)
+
+	ctx := ct.Request.Context()
+	span := trace.SpanFromContext(ctx)
+
+	span.SetAttributes(attribute.String("shortlink", shortlinkName))
+
+	ct.Header("Cache-Control", "no-store")
+
+	if user.Login == "" {
+		s.renderNotFound(ct, http.StatusUnauthorized, shortlinkName, nil)
+		return
+	}
+
+	// The session cookie is sent along with requests from other sites as well, so the form has to prove it came from us
+	if !s.isValidCSRFToken(ct, ct.PostForm("csrfToken")) {
+		span.AddEvent("invalid csrf token")
+
+		s.renderCreateError(ct, http.StatusForbidden, shortlinkName, "The form expired, please submit it again.")
+		return
+	}
+
+	if shortlinkName == "" || target == "" {
+		s.renderCreateError(ct, http.StatusBadRequest, shortlinkName, "Name and target are required.")
+		return
+	}
+
+	shortlink := v1alpha1.Shortlink{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: shortlinkName,
+		},
+		Spec: v1alpha1.ShortlinkSpec{
+			Target: target,
+		},
+	}
+
+	nameShortlink(&shortlink, shortlinkName)
+
+	if err := s.userClient.Create(ctx, user, &shortlink); err != nil {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to create ShortLink",
+			zap.String("shortlink", shortlinkName),
+			zap.String("operation", "create"),
+		)
+
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "already in use") {
+			statusCode = http.StatusConflict
+		} else if strings.Contains(err.Error(), "not allowed by policy") || strings.Contains(err.Error(), "is not a valid rule") {
+			statusCode = http.StatusBadRequest
+		}
+
+		s.renderCreateError(ct, statusCode, shortlinkName, err.Error())
+		return
+	}
+
+	// Show the new shortlink on its info page
+	ct.Redirect(http.StatusSeeOther, "/"+shortlinkName+"+")
+}
+
+func (s *UrlshortenerServer) renderCreateError(ct *gin.Context, statusCode int, shortlinkName string, message string) {
+	s.renderNotFound(ct, statusCode, shortlinkName, gin.H{"createError": message})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSRFToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	s := &UrlshortenerServer{csrfTokens: newCookieSigner(key, csrfTokenPurpose)}
+	otherKey := &UrlshortenerServer{csrfTokens: newCookieSigner(key, sessionCookiePurpose)}
+
+	withSession := func(session string) *gin.Context {
+		ct, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ct.Request = httptest.NewRequest("POST", "/_/create", nil)
+		if session != "" {
+			ct.Request.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+		}
+		return ct
+	}
+
+	token := s.csrfToken(withSession("session-of-alice"))
+	if token == "" {
+		t.Fatal("csrfToken() returned no token for a session")
+	}
+
+	tests := []struct {
+		name    string
+		server  *UrlshortenerServer
+		session string
+		token   string
+		want    bool
+	}{
+		{name: "token of the session", server: s, session: "session-of-alice", token: token, want: true},
+		{name: "token of another session", server: s, session: "session-of-bob", token: token, want: false},
+		{name: "no session", server: s, session: "", token: token, want: false},
+		{name: "no token", server: s, session: "session-of-alice", token: "", want: false},
+		{name: "tampered token", server: s, session: "session-of-alice", token: token + "x", want: false},
+		{name: "token signed for another purpose", server: otherKey, session: "session-of-alice", token: token, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.server.isValidCSRFToken(withSession(tt.session), tt.token); got != tt.want {
+				t.Errorf("isValidCSRFToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
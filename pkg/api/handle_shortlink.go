@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -11,6 +12,10 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
 )
 
 // HandleShortLink handles the shortlink and redirects according to the configuration
@@ -20,6 +25,8 @@ import (
 // @Description   redirect to target as per configuration of the shortlink
 // @Produce       text/html
 // @Param         shortlink   path      string  true  "shortlink id"
+// @Param         rest        path      string  false "path passed through to the target"
+// @Param         preview     query     string  false "show the info page of the shortlink instead of redirecting"
 // @Success       200         {object}  int     "Success"
 // @Success       300         {object}  int     "MultipleChoices"
 // @Success       301         {object}  int     "MovedPermanently"
@@ -29,13 +36,15 @@ import (
 // @Success       305         {object}  int     "UseProxy"
 // @Success       307         {object}  int     "TemporaryRedirect"
 // @Success       308         {object}  int     "PermanentRedirect"
+// @Failure       401         {object}  int     "Unauthorized"
 // @Failure       404         {object}  int     "NotFound"
+// @Failure       410         {object}  int     "Gone"
 // @Failure       500         {object}  int     "InternalServerError"
+// @Failure       503         {object}  int     "ServiceUnavailable"
 // @Tags default
 // @Router /{shortlink} [get]
+// @Router /{shortlink}/{rest} [get]
 func (s *UrlshortenerServer) HandleShortLink(ct *gin.Context) {
-	shortlinkName := ct.Param("shortlink")
-
 	ctx := ct.Request.Context()
 	span := trace.SpanFromContext(ctx)
 
@@ -45,14 +54,15 @@ func (s *UrlshortenerServer) HandleShortLink(ct *gin.Context) {
 		defer span.End()
 	}
 
+	ct.Header("Cache-Control", "public, max-age=900, stale-if-error=3600") // max-age = 15min; stale-if-error = 1h
+
+	shortlink, shortlinkName, rest, preview, err := s.resolveShortlinkRequest(ctx, ct)
+
 	span.SetAttributes(
 		attribute.String("shortlink", shortlinkName),
 		attribute.String("referrer", ct.Request.Referer()),
 	)
 
-	ct.Header("Cache-Control", "public, max-age=900, stale-if-error=3600") // max-age = 15min; stale-if-error = 1h
-
-	shortlink, err := s.client.Get(ctx, shortlinkName)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			otelzap.L().WithError(err).Ctx(ctx).Error("Path not found",
@@ -62,7 +72,7 @@ func (s *UrlshortenerServer) HandleShortLink(ct *gin.Context) {
 
 			span.SetAttributes(attribute.String("path", ct.Request.URL.Path))
 
-			ct.HTML(http.StatusNotFound, "404.html", gin.H{})
+			s.renderNotFound(ct, http.StatusNotFound, shortlinkName, nil)
 		} else {
 			otelzap.L().WithError(err).Ctx(ctx).Error("Failed to get ShortLink",
 				zap.String("shortlink", shortlinkName),
@@ -74,26 +84,178 @@ func (s *UrlshortenerServer) HandleShortLink(ct *gin.Context) {
 		return
 	}
 
+	// Non-public shortlinks are only followed for signed-in visitors that may see them
+	if !s.authorizeViewer(ct, shortlink) {
+		return
+	}
+
+	now := time.Now()
+
+	// Expired shortlinks are gone for good
+	if reason := s.expiryReason(shortlink, now); reason != "" {
+		otelzap.L().Ctx(ctx).Info("Shortlink expired",
+			zap.String("shortlink", shortlinkName),
+			zap.String("reason", reason),
+			zap.String("operation", "shortlink"),
+		)
+
+		span.SetAttributes(attribute.String("expired", reason))
+
+		ct.HTML(http.StatusGone, "410.html", gin.H{"shortlink": shortlinkName})
+		return
+	}
+
+	// Shortlinks that are not active yet are treated as if they did not exist
+	if !shortlink.IsActive(now) {
+		otelzap.L().Ctx(ctx).Info("Shortlink not active yet",
+			zap.String("shortlink", shortlinkName),
+			zap.Time("notBefore", shortlink.Spec.NotBefore.Time),
+			zap.String("operation", "shortlink"),
+		)
+
+		span.SetAttributes(attribute.String("path", ct.Request.URL.Path))
+
+		s.renderNotFound(ct, http.StatusNotFound, shortlinkName, nil)
+		return
+	}
+
+	// Only shortlinks that are live may reveal where they lead
+	if preview {
+		span.SetAttributes(attribute.Bool("preview", true))
+
+		s.renderPreview(ct, shortlink, shortlinkName, now)
+		return
+	}
+
+	// Every invocation of an expiring, scheduled, rule-based, split, protected or failover shortlink has to reach us, so it must not be cached
+	if shortlink.Spec.FallbackTarget != "" || shortlink.Spec.PasswordSecretRef != nil || shortlink.Spec.ExpiresAt != nil || shortlink.Spec.NotBefore != nil || shortlink.Spec.MaxClicks > 0 || len(shortlink.Spec.Schedule) > 0 || len(shortlink.Spec.Rules) > 0 || len(shortlink.Spec.Variants) > 0 {
+		ct.Header("Cache-Control", "no-store")
+	}
+
+	// Password-protected shortlinks only redirect once they have been unlocked
+	if shortlink.Spec.PasswordSecretRef != nil {
+		if !s.isUnlocked(ct, shortlink) {
+			span.AddEvent("password required")
+
+			ct.HTML(http.StatusUnauthorized, "password.html", gin.H{"shortlink": shortlinkName})
+			return
+		}
+	}
+
+	liveTarget, code := shortlink.ActiveTarget(now)
+	activeTarget := liveTarget
+
+	// The first matching targeting rule overrides the target
+	var rule *v1alpha1.TargetRule
+	if len(shortlink.Spec.Rules) > 0 {
+		if rule = s.matchRule(ctx, shortlink, newRuleRequest(ct, now)); rule != nil {
+			span.AddEvent("rule matched", trace.WithAttributes(
+				attribute.String("expression", rule.Expression),
+				attribute.String("target", rule.Target),
+			))
+
+			activeTarget = rule.Target
+			if rule.Code != 0 {
+				code = rule.Code
+			}
+		}
+	}
+
+	// Without a matching rule, the traffic is split between the variants
+	variantName := ""
+	if rule == nil && len(shortlink.Spec.Variants) > 0 {
+		if variant := s.assignVariant(ct, shortlink); variant != nil {
+			span.AddEvent("variant picked", trace.WithAttributes(
+				attribute.String("variant", variant.Name),
+				attribute.String("target", variant.Target),
+			))
+
+			activeTarget = variant.Target
+			variantName = variant.Name
+		}
+	}
+
+	// While the live target is down, visitors are sent to the fallback target or told about the outage
+	if activeTarget == liveTarget && !shortlink.IsTargetReachable() {
+		span.AddEvent("target unreachable", trace.WithAttributes(
+			attribute.String("target", activeTarget),
+			attribute.String("fallbackTarget", shortlink.Spec.FallbackTarget),
+		))
+
+		if shortlink.Spec.FallbackTarget == "" {
+			otelzap.L().Ctx(ctx).Info("Shortlink target is down",
+				zap.String("shortlink", shortlinkName),
+				zap.String("target", activeTarget),
+				zap.String("operation", "shortlink"),
+			)
+
+			data := gin.H{"shortlink": shortlinkName}
+			if !isTargetTemplate(activeTarget) {
+				data["target"] = activeTarget
+			}
+
+			ct.Header("Cache-Control", "no-store")
+			ct.HTML(http.StatusServiceUnavailable, "down.html", data)
+			return
+		}
+
+		activeTarget = shortlink.Spec.FallbackTarget
+	}
+	templated := isTargetTemplate(activeTarget)
+
+	// Additional path segments are only valid if the shortlink consumes or passes them through
+	if rest != "" && !templated && !shortlink.Spec.Passthrough.Path {
+		otelzap.L().Ctx(ctx).Info("Path not found",
+			zap.String("shortlink", shortlinkName),
+			zap.String("path", ct.Request.URL.Path),
+			zap.String("operation", "shortlink"),
+		)
+
+		span.SetAttributes(attribute.String("path", ct.Request.URL.Path))
+
+		s.renderNotFound(ct, http.StatusNotFound, strings.Trim(ct.Request.URL.Path, "/"), nil)
+		return
+	}
+
 	span.SetAttributes(
-		attribute.String("Target", shortlink.Spec.Target),
-		attribute.Int64("RedirectAfter", shortlink.Spec.RedirectAfter),
+		attribute.String("Target", activeTarget),
+		attribute.Int64("RedirectAfter", shortlink.RedirectDelay()),
 		attribute.Int("InvocationCount", shortlink.Status.Count),
 	)
 
-	target := shortlink.Spec.Target
+	target := expandTarget(activeTarget, shortlink.Spec.NoArgsTarget, splitPath(rest), ct.Request.URL.Query())
 
 	if !strings.HasPrefix(target, "http") {
+		from := target
 		target = fmt.Sprintf("http://%s", target)
 
 		span.AddEvent("change prefix", trace.WithAttributes(
-			attribute.String("from", shortlink.Spec.Target),
+			attribute.String("from", from),
 			attribute.String("to", target),
 		))
 	}
 
-	if shortlink.Spec.Code != 200 {
+	// A templated target already consumed the arguments of the request
+	if !templated {
+		if target, err = applyPassthrough(target, shortlink.Spec.Passthrough, rest, ct.Request.URL.RawQuery); err != nil {
+			otelzap.L().WithError(err).Ctx(ctx).Error("Failed to pass request through to target",
+				zap.String("shortlink", shortlinkName),
+				zap.String("operation", "shortlink"),
+			)
+
+			ct.HTML(http.StatusInternalServerError, "500.html", gin.H{})
+			return
+		}
+	}
+
+	// Shortlinks created while the defaulting webhook was disabled may lack a code
+	if code == 0 {
+		code = v1alpha1.DefaultRedirectCode
+	}
+
+	if code != 200 {
 		// Redirect
-		ct.Redirect(shortlink.Spec.Code, target)
+		ct.Redirect(code, target)
 	} else {
 		// Redirect via JS/HTML
 		ct.HTML(
@@ -102,13 +264,26 @@ func (s *UrlshortenerServer) HandleShortLink(ct *gin.Context) {
 			gin.H{
 				"redirectFrom":  ct.Request.URL.Path,
 				"redirectTo":    target,
-				"redirectAfter": shortlink.Spec.RedirectAfter,
+				"redirectAfter": shortlink.RedirectDelay(),
 			},
 		)
 	}
 
 	// Increase hit counter
-	if err := s.client.IncrementInvocationCount(ct, shortlink); err != nil {
-		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to increment invocation count")
+	s.counter.Increment(types.NamespacedName{Name: shortlink.Name, Namespace: shortlink.Namespace}, variantName)
+}
+
+// expiryReason returns why the shortlink is expired at the given point in time, or an empty string if it is not.
+// The invocations this replica has not written to the status yet count towards the click budget as well.
+func (s *UrlshortenerServer) expiryReason(shortlink *v1alpha1.Shortlink, now time.Time) string {
+	if reason := shortlink.ExpiryReason(now); reason != "" {
+		return reason
+	}
+
+	pending := s.counter.Pending(types.NamespacedName{Name: shortlink.Name, Namespace: shortlink.Namespace})
+	if shortlink.Spec.MaxClicks > 0 && shortlink.Status.Count+pending >= shortlink.Spec.MaxClicks {
+		return v1alpha1.ShortlinkReasonClickBudgetExhausted
 	}
+
+	return ""
 }
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+
+// unlockCookieMaxAge is how long a password-protected shortlink stays unlocked after entering the password
+const unlockCookieMaxAge = 15 * time.Minute
+
+// errPasswordSecretUnusable is returned if the Secret holding the password of a shortlink does not exist,
+// lacks the password label or the selected key. Visitors cannot tell this apart from a wrong password.
+var errPasswordSecretUnusable = errors.New("password secret is not usable")
+
+// unusablePasswordHash is compared against the entered password if the password Secret is unusable, so the response
+// takes as long as for a wrong password. It is never accepted, whichever password is entered.
+var unusablePasswordHash = []byte("$2a$10$XNwC7ACHkWwHKAzAgdwdVurFSH40n1o9MoOYYHHLggbWpBp42wHZC")
+
+// unlockCookieName returns the name of the cookie unlocking a password-protected shortlink
+func unlockCookieName(shortlink *v1alpha1.Shortlink) string {
+	return "urlshortener_unlock_" + shortlink.Name
+}
+
+// isUnlocked returns true if the request carries a valid unlock cookie for the shortlink.
+// The cookie is bound to the UID of the shortlink, so it does not unlock a re-created shortlink of the same name.
+func (s *UrlshortenerServer) isUnlocked(ct *gin.Context, shortlink *v1alpha1.Shortlink) bool {
+	cookie, err := ct.Cookie(unlockCookieName(shortlink))
+	if err != nil {
+		return false
+	}
+
+	uid, ok := s.unlockCookies.Verify(cookie, time.Now())
+	return ok && uid == string(shortlink.UID)
+}
+
+// unlock sets the cookie unlocking the shortlink. It applies to all paths, so the shortlink is unlocked under its name,
+// slug and aliases in any spelling. The cookie is named after the shortlink and bound to its UID, so it unlocks no other one.
+func (s *UrlshortenerServer) unlock(ct *gin.Context, shortlink *v1alpha1.Shortlink, now time.Time) {
+	s.setCookie(ct, unlockCookieName(shortlink), s.unlockCookies.Sign(string(shortlink.UID), now.Add(unlockCookieMaxAge)), unlockCookieMaxAge, "/")
+}
+
+// HandleShortLinkPassword checks the password of a password-protected shortlink and unlocks it
+// @BasePath /
+// @Summary       unlock a password-protected shortlink
+// @Schemes       http https
+// @Description   check the password of a password-protected shortlink and redirect back to it
+// @Accept        application/x-www-form-urlencoded
+// @Produce       text/html
+// @Param         shortlink   path      string  true  "shortlink id"
+// @Param         rest        path      string  false "path passed through to the target"
+// @Param         password    formData  string  true  "password of the shortlink"
+// @Success       303         {object}  int     "SeeOther"
+// @Failure       401         {object}  int     "Unauthorized"
+// @Failure       404         {object}  int     "NotFound"
+// @Failure       429         {object}  int     "TooManyRequests"
+// @Failure       500         {object}  int     "InternalServerError"
+// @Tags default
+// @Router /{shortlink} [post]
+// @Router /{shortlink}/{rest} [post]
+func (s *UrlshortenerServer) HandleShortLinkPassword(ct *gin.Context) {
+	ctx := ct.Request.Context()
+	span := trace.SpanFromContext(ctx)
+
+	// Check if the span was sampled and is recording the data
+	if !span.IsRecording() {
+		ctx, span = s.tracer.Start(ctx, "ShortlinkController.HandleShortLinkPassword")
+		defer span.End()
+	}
+
+	ct.Header("Cache-Control", "no-store")
+
+	shortlink, shortlinkName, _, _, err := s.resolveShortlinkRequest(ctx, ct)
+
+	span.SetAttributes(attribute.String("shortlink", shortlinkName))
+
+	if err == nil && !s.authorizeViewer(ct, shortlink) {
+		return
+	}
+
+	if err != nil || shortlink.Spec.PasswordSecretRef == nil {
+		if err == nil || strings.Contains(err.Error(), "not found") {
+			ct.HTML(http.StatusNotFound, "404.html", gin.H{})
+		} else {
+			otelzap.L().WithError(err).Ctx(ctx).Error("Failed to get ShortLink",
+				zap.String("shortlink", shortlinkName),
+				zap.String("operation", "password"),
+			)
+
+			ct.HTML(http.StatusInternalServerError, "500.html", gin.H{})
+		}
+		return
+	}
+
+	// Every client and every shortlink only get a few attempts per window, so passwords cannot be guessed online.
+	// The client IP is only taken from forwarded headers of trusted proxies, so clients cannot pick a fresh one per attempt.
+	now := time.Now()
+	if !s.clientPasswordAttempts.Allow(ct.ClientIP(), now) || !s.shortlinkPasswordAttempts.Allow(shortlink.Namespace+"/"+shortlink.Name, now) {
+		otelzap.L().Ctx(ctx).Warn("Too many password attempts for ShortLink",
+			zap.String("shortlink", shortlinkName),
+			zap.String("client", ct.ClientIP()),
+			zap.String("operation", "password"),
+		)
+
+		span.AddEvent("too many password attempts")
+
+		ct.HTML(http.StatusTooManyRequests, "password.html", gin.H{"shortlink": shortlinkName, "tooManyAttempts": true})
+		return
+	}
+
+	hash, err := s.passwordHash(ct, shortlink)
+	if err != nil && !errors.Is(err, errPasswordSecretUnusable) {
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to get password of ShortLink",
+			zap.String("shortlink", shortlinkName),
+			zap.String("operation", "password"),
+		)
+
+		ct.HTML(http.StatusInternalServerError, "500.html", gin.H{})
+		return
+	}
+
+	unusable := err != nil
+	if unusable {
+		otelzap.L().WithError(err).Ctx(ctx).Warn("Password secret of ShortLink is not usable",
+			zap.String("shortlink", shortlinkName),
+			zap.String("operation", "password"),
+		)
+
+		hash = unusablePasswordHash
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(ct.PostForm("password"))); err != nil || unusable {
+		otelzap.L().Ctx(ctx).Info("Wrong password for ShortLink",
+			zap.String("shortlink", shortlinkName),
+			zap.String("operation", "password"),
+		)
+
+		span.AddEvent("wrong password")
+
+		ct.HTML(http.StatusUnauthorized, "password.html", gin.H{"shortlink": shortlinkName, "wrongPassword": true})
+		return
+	}
+
+	s.unlock(ct, shortlink, now)
+
+	// Send the visitor back to the shortlink, which now redirects as usual
+	ct.Redirect(http.StatusSeeOther, ct.Request.URL.RequestURI())
+}
+
+// passwordHash reads the bcrypt hash of the password of a shortlink from the referenced Secret.
+// The Secret is read directly from the API server, so the server does not need to cache all Secrets.
+// Only Secrets labelled with PasswordSecretLabel may hold passwords, so shortlinks cannot probe other Secrets.
+func (s *UrlshortenerServer) passwordHash(ct *gin.Context, shortlink *v1alpha1.Shortlink) ([]byte, error) {
+	ref := shortlink.Spec.PasswordSecretRef
+
+	secret := &corev1.Secret{}
+	if err := s.reader.Get(ct.Request.Context(), types.NamespacedName{Name: ref.Name, Namespace: shortlink.Namespace}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Wrapf(errPasswordSecretUnusable, "Password secret %s not found", ref.Name)
+		}
+
+		return nil, errors.Wrap(err, "Unable to get password secret")
+	}
+
+	if secret.Labels[v1alpha1.PasswordSecretLabel] != "true" {
+		return nil, errors.Wrapf(errPasswordSecretUnusable, "Password secret %s is not labelled %s=true", ref.Name, v1alpha1.PasswordSecretLabel)
+	}
+
+	hash, ok := secret.Data[ref.Key]
+	if !ok || len(hash) == 0 {
+		return nil, errors.Wrapf(errPasswordSecretUnusable, "Password secret %s has no key %s", ref.Name, ref.Key)
+	}
+
+	return hash, nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+func TestUnlockAppliesToAllNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s := &UrlshortenerServer{unlockCookies: newCookieSigner([]byte("0123456789abcdef0123456789abcdef"), unlockCookiePurpose)}
+	shortlink := &v1alpha1.Shortlink{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-link-3fa9c2e1b0", Namespace: "default", UID: "uid-1"},
+		Spec: v1alpha1.ShortlinkSpec{
+			Slug:    "my-link",
+			Aliases: []string{"docs", "handbook"},
+		},
+	}
+
+	// Unlock through an alias
+	recorder := httptest.NewRecorder()
+	ct, _ := gin.CreateTestContext(recorder)
+	ct.Request = httptest.NewRequest("POST", "/Docs", nil)
+	s.unlock(ct, shortlink, time.Now())
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("unlock() set %d cookies, want 1", len(cookies))
+	}
+
+	if cookies[0].Path != "/" {
+		t.Errorf("cookie path = %q, want /", cookies[0].Path)
+	}
+
+	recreated := shortlink.DeepCopy()
+	recreated.UID = "uid-2"
+
+	tests := []struct {
+		name      string
+		path      string
+		shortlink *v1alpha1.Shortlink
+		want      bool
+	}{
+		{name: "alias it was unlocked through", path: "/Docs", shortlink: shortlink, want: true},
+		{name: "slug", path: "/my-link", shortlink: shortlink, want: true},
+		{name: "slug in another spelling", path: "/My_Link", shortlink: shortlink, want: true},
+		{name: "other alias", path: "/handbook", shortlink: shortlink, want: true},
+		{name: "re-created shortlink", path: "/my-link", shortlink: recreated, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ct.Request = httptest.NewRequest("GET", tt.path, nil)
+
+			// Browsers only send the cookie along if its path matches the request
+			if strings.HasPrefix(tt.path, cookies[0].Path) {
+				ct.Request.AddCookie(cookies[0])
+			}
+
+			if got := s.isUnlocked(ct, tt.shortlink); got != tt.want {
+				t.Errorf("isUnlocked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// renderPreview renders the info page of a live shortlink, showing where it leads and who owns it.
+// The target of a password-protected shortlink is only shown once it has been unlocked.
+// shortlinkName is the name the shortlink was requested by, which may be one of its aliases or its path.
+func (s *UrlshortenerServer) renderPreview(ct *gin.Context, shortlink *v1alpha1.Shortlink, shortlinkName string, now time.Time) {
+	ct.Header("Cache-Control", "no-store")
+
+	if shortlink.Spec.PasswordSecretRef != nil && !s.isUnlocked(ct, shortlink) {
+		ct.HTML(http.StatusUnauthorized, "password.html", gin.H{"shortlink": shortlinkName})
+		return
+	}
+
+	currentTarget, code := shortlink.ActiveTarget(now)
+
+	ct.HTML(http.StatusOK, "preview.html", gin.H{
+		"shortlink":     shortlinkName,
+		"path":          shortlink.Spec.Path,
+		"aliases":       shortlink.Spec.Aliases,
+		"domains":       shortlink.Spec.Domains,
+		"target":        shortlink.Spec.Target,
+		"currentTarget": currentTarget,
+		"fallback":      shortlink.Spec.FallbackTarget,
+		"reachable":     shortlink.IsTargetReachable(),
+		"variants":      shortlink.Spec.Variants,
+		"rules":         len(shortlink.Spec.Rules),
+		"owner":         shortlink.Spec.Owner,
+		"coOwners":      shortlink.Spec.CoOwners,
+		"redirectAfter": shortlink.RedirectDelay(),
+		"code":          code,
+		"count":         shortlink.Status.Count,
+		"changedBy":     shortlink.Status.ChangedBy,
+		"lastModified":  shortlink.Status.LastModified,
+	})
+}
@@ -26,24 +26,29 @@ import (
 // @Produce       text/plain
 // @Produce       application/json
 // @Param         shortlink   path      string                 true   "the shortlink URL part (shortlink id)" example(home)
-// @Param         spec        body      v1alpha1.ShortLinkSpec true   "shortlink spec"
+// @Param         path        path      string                 false  "the rest of a hierarchical shortlink path, e.g. runbook for sre/runbook"
+// @Param         domain      query     string                 false  "the domain the shortlink is served under, if the name is used under several domains"
+// @Param         spec        body      v1alpha1.ShortlinkSpec true   "shortlink spec"
 // @Success       200         {object}  int     "Success"
+// @Failure       400         {object}  int     "BadRequest"
 // @Failure       401         {object}  int     "Unauthorized"
 // @Failure       404         {object}  int     "NotFound"
+// @Failure       409         {object}  int     "Conflict"
 // @Failure       500         {object}  int     "InternalServerError"
 // @Tags api/v1/
 // @Router /api/v1/shortlink/{shortlink} [put]
+// @Router /api/v1/shortlink/{shortlink}/{path} [put]
 // @Security bearerAuth
 func (s *UrlshortenerServer) HandleUpdateShortLink(ct *gin.Context) {
-	shortlinkName := ct.Param("shortlink")
-	userName := ct.GetString("githubUserName")
+	shortlinkName := apiShortlinkParam(ct)
+	user := apiUser(ct)
 
 	ctx := ct.Request.Context()
 	span := trace.SpanFromContext(ctx)
 
 	span.SetAttributes(attribute.String("shortlink", shortlinkName), attribute.String("referrer", ct.Request.Referer()))
 
-	if len(userName) == 0 {
+	if len(user.Login) == 0 {
 		err := humane.New("No user found for request",
 			"ensure you include a Bearer token in the Authorization header, e.g. Authorization: Bearer <token> or Authorization: token <token>",
 		)
@@ -83,12 +88,9 @@ func (s *UrlshortenerServer) HandleUpdateShortLink(ct *gin.Context) {
 		return
 	}
 
-	shortlink, err := s.userClient.Get(ctx, userName, shortlinkName)
+	shortlink, err := s.getUserShortlink(ctx, user, shortlinkName, apiShortlinkDomain(ct))
 	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if strings.Contains(err.Error(), "not found") {
-			statusCode = http.StatusNotFound
-		}
+		statusCode := lookupErrorStatusCode(err)
 
 		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to get ShortLink",
 			zap.String("shortlink", shortlinkName),
@@ -104,15 +106,33 @@ func (s *UrlshortenerServer) HandleUpdateShortLink(ct *gin.Context) {
 		ct.JSON(http.StatusInternalServerError, gin.H{"error": "Shortlink not found"})
 		return
 	}
+
+	// The slug is the identity the object name was derived from, so it is always kept
+	if shortlinkSpec.Slug == "" {
+		shortlinkSpec.Slug = shortlink.Spec.Slug
+	}
+
+	// A shortlink addressed by its path keeps it, unless the spec moves it elsewhere
+	if isShortlinkPath(shortlinkName) && shortlinkSpec.Path == "" {
+		shortlinkSpec.Path = shortlink.Spec.Path
+	}
+
 	shortlink.Spec = shortlinkSpec
 
-	if err := s.userClient.Update(ctx, userName, shortlink); err != nil {
+	if err := s.userClient.Update(ctx, user, shortlink); err != nil {
 		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to update ShortLink",
 			zap.String("shortlink", shortlinkName),
 			zap.String("operation", "update"),
 		)
 
-		ct.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		statusCode := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "already in use") {
+			statusCode = http.StatusConflict
+		} else if strings.Contains(err.Error(), "not allowed by policy") || strings.Contains(err.Error(), "is not a valid rule") {
+			statusCode = http.StatusBadRequest
+		}
+
+		ct.JSON(statusCode, gin.H{"error": err.Error()})
 		return
 	}
 
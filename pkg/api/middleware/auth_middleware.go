@@ -33,13 +33,16 @@ func GitHubUserAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if user, err := getGitHubUserInfo(ctx, tokenString); err != nil {
+		if user, err := GetGitHubUserInfo(ctx, tokenString); err != nil {
 			otelzap.L().WithError(err).Ctx(ctx).Error(err.Error(),
 				zap.String("shortlink", shortlinkName),
 				zap.String("method", c.Request.Method),
 			)
 		} else {
-			c.Set("githubUserName", user.Name)
+			// The login is unique, unlike the display name which every user can choose freely
+			c.Set("githubUserName", user.Login)
+			// Shortlinks created before are owned by the display name, until they are migrated to the login
+			c.Set("githubDisplayName", user.Name)
 		}
 
 		c.Next()
@@ -41,7 +41,8 @@ func extractBearerToken(c *gin.Context) (string, humane.Error) {
 	return parts[1], nil
 }
 
-func getGitHubUserInfo(c context.Context, bearerToken string) (*GithubUser, error) {
+// GetGitHubUserInfo fetches the GitHub user the bearer token belongs to
+func GetGitHubUserInfo(c context.Context, bearerToken string) (*GithubUser, error) {
 	// prepare request to GitHubs User endpoint
 	req, err := http.NewRequestWithContext(c, http.MethodGet, "https://api.github.com/user", nil)
 	if err != nil {
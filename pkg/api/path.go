@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+	shortlinkClient "github.com/spechtlabs/urlshortener/pkg/client"
+	"github.com/spechtlabs/urlshortener/pkg/slug"
+)
+
+// resolveShortlinkRequest resolves the shortlink serving the path of a request under its host by its longest matching name or path.
+// It returns the shortlink, the name or path it matched, the remaining path following it, and whether the visitor
+// asked for the preview of the shortlink instead of being redirected, either by a trailing "+" or the query parameter
+// "preview". If no shortlink matches, the returned name is the full path of the request.
+func (s *UrlshortenerServer) resolveShortlinkRequest(ctx context.Context, ct *gin.Context) (*v1alpha1.Shortlink, string, string, bool, error) {
+	requestPath := ct.Param("shortlink") + ct.Param("rest")
+	_, preview := ct.GetQuery("preview")
+
+	// A trailing "+" asks for the preview, unless it is part of the path passed on to the target
+	if trimmed, found := strings.CutSuffix(requestPath, "+"); found {
+		if shortlink, name, rest, err := s.resolver.ResolvePath(ctx, ct.Request.Host, trimmed); err == nil && rest == "" {
+			return shortlink, name, rest, true, nil
+		}
+	}
+
+	shortlink, name, rest, err := s.resolver.ResolvePath(ctx, ct.Request.Host, requestPath)
+	if err != nil {
+		return nil, strings.Trim(requestPath, "/"), "", preview, err
+	}
+
+	return shortlink, name, rest, preview, nil
+}
+
+// apiShortlinkParam returns the shortlink addressed by an API request, either a name or a path such as sre/runbook
+func apiShortlinkParam(ct *gin.Context) string {
+	return strings.Trim(ct.Param("shortlink")+ct.Param("path"), "/")
+}
+
+// isShortlinkPath returns true if name is the hierarchical path of a shortlink rather than its name
+func isShortlinkPath(name string) bool {
+	return strings.Contains(name, "/")
+}
+
+// apiShortlinkDomain returns the domain an API request addresses the shortlink under, set by the query parameter "domain".
+// The same name may be used under several domains, so the domain selects one of them.
+func apiShortlinkDomain(ct *gin.Context) string {
+	return ct.Query("domain")
+}
+
+// getUserShortlink returns the shortlink with the given name or path served under the given domain, if the user may see it.
+// An empty domain matches shortlinks under every domain, as long as only one of them uses the name.
+func (s *UrlshortenerServer) getUserShortlink(ctx context.Context, user shortlinkClient.User, name string, domain string) (*v1alpha1.Shortlink, error) {
+	if isShortlinkPath(name) {
+		shortlink, err := s.resolver.Resolve(ctx, domain, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.userClient.Get(ctx, user, shortlink.Name, "")
+	}
+
+	return s.userClient.Get(ctx, user, name, domain)
+}
+
+// lookupErrorStatusCode maps an error looking up the shortlink of an API request to the HTTP status code of the response
+func lookupErrorStatusCode(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "is ambiguous"):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// nameShortlink stores the shortlink under the given name. Names that are not valid object names are stored
+// under an object name derived from them, and hierarchical names are stored as the path of the shortlink.
+func nameShortlink(shortlink *v1alpha1.Shortlink, name string) {
+	objectName := name
+
+	// The same name may be used under every domain, so the first domain becomes part of the object name, e.g. docs.go.corp
+	if len(shortlink.Spec.Domains) > 0 {
+		objectName = name + "." + shortlink.Spec.Domains[0]
+	}
+
+	shortlink.Name = slug.ObjectName(objectName)
+	if isShortlinkPath(name) {
+		shortlink.Spec.Path = name
+	} else if shortlink.Name != name {
+		shortlink.Spec.Slug = name
+	}
+}
+
+// shortlinkURL returns the full short URL of the shortlink with the given name on the server serving the request
+func shortlinkURL(ct *gin.Context, name string) string {
+	return requestBaseURL(ct) + "/" + name
+}
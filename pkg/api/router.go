@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sierrasoftworks/humane-errors-go"
+	"golang.org/x/oauth2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	ginzap "github.com/gin-contrib/zap"
@@ -19,8 +20,12 @@ import (
 	"go.uber.org/zap/zapcore"
 
 	"github.com/spechtlabs/urlshortener/docs"
+	"github.com/spechtlabs/urlshortener/internal/controller"
 	"github.com/spechtlabs/urlshortener/pkg/api/middleware"
 	shortlinkClient "github.com/spechtlabs/urlshortener/pkg/client"
+	"github.com/spechtlabs/urlshortener/pkg/policy"
+	"github.com/spechtlabs/urlshortener/pkg/rules"
+	"github.com/spechtlabs/urlshortener/pkg/slug"
 
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
@@ -50,22 +55,66 @@ type UrlshortenerServer struct {
 	tracer     trace.Tracer
 	userClient *shortlinkClient.UserShortLinkClient
 	client     *shortlinkClient.ShortlinkClient
+	resolver   *controller.ShortlinkResolver
+	counter    *shortlinkClient.InvocationCounter
+	rules      *rules.Evaluator
+	reader     client.Reader
+	oauth      *oauth2.Config
+	slugs      *slug.Generator
+
+	sessionCookies    *cookieSigner
+	unlockCookies     *cookieSigner
+	oauthStateCookies *cookieSigner
+	csrfTokens        *cookieSigner
+
+	clientPasswordAttempts    *attemptLimiter
+	shortlinkPasswordAttempts *attemptLimiter
 }
 
-// NewGinGonicHTTPServer creates a new urlshortener API Server
-func NewGinGonicHTTPServer(client client.Client) *UrlshortenerServer {
+// NewGinGonicHTTPServer creates a new urlshortener API Server.
+// The reader is used for objects that are not cached, such as Secrets, and cookieKey signs the cookies issued by the server.
+// It has to be at least MinCookieKeyLength bytes long.
+// oauth configures the GitHub login of browser sessions, which is disabled if it is nil.
+// slugs generates the names of shortlinks that are created without one, and targetPolicy restricts the targets users may set.
+// trustedProxies are the addresses or CIDRs of the proxies whose X-Forwarded-For and X-Real-IP headers name the client.
+// If it is empty, no header is trusted and the client is the remote address of the connection.
+func NewGinGonicHTTPServer(client client.Client, reader client.Reader, resolver *controller.ShortlinkResolver, counter *shortlinkClient.InvocationCounter, cookieKey []byte, oauth *oauth2.Config, slugs *slug.Generator, targetPolicy *policy.Policy, trustedProxies []string) *UrlshortenerServer {
 	sClient := shortlinkClient.NewShortlinkClient(client)
 
+	ruleEvaluator, err := rules.NewEvaluator()
+	if err != nil {
+		otelzap.L().WithError(err).Fatal("Unable to create targeting rule evaluator")
+	}
+
 	r := &UrlshortenerServer{
 		srv:        nil,
 		tracer:     otel.Tracer("urlshortener"),
-		userClient: shortlinkClient.NewUserShortLinkClient(sClient),
+		userClient: shortlinkClient.NewUserShortLinkClient(sClient, targetPolicy, ruleEvaluator),
 		client:     sClient,
+		resolver:   resolver,
+		counter:    counter,
+		rules:      ruleEvaluator,
+		reader:     reader,
+		oauth:      oauth,
+		slugs:      slugs,
+
+		sessionCookies:    newCookieSigner(cookieKey, sessionCookiePurpose),
+		unlockCookies:     newCookieSigner(cookieKey, unlockCookiePurpose),
+		oauthStateCookies: newCookieSigner(cookieKey, oauthStateCookiePurpose),
+		csrfTokens:        newCookieSigner(cookieKey, csrfTokenPurpose),
+
+		clientPasswordAttempts:    newAttemptLimiter(passwordAttemptsPerClient, passwordAttemptWindow),
+		shortlinkPasswordAttempts: newAttemptLimiter(passwordAttemptsPerShortlink, passwordAttemptWindow),
 	}
 
 	// Setup Gin router
 	r.router = gin.New(func(e *gin.Engine) {})
 
+	// Targeting rules and password throttling rely on the client IP, so it must not be taken from headers anyone can set
+	if err := r.router.SetTrustedProxies(trustedProxies); err != nil {
+		otelzap.L().WithError(err).Fatal("Invalid trusted proxies")
+	}
+
 	// Setup otelgin to expose Open Telemetry
 	r.router.Use(otelgin.Middleware("gin"))
 
@@ -128,8 +177,20 @@ func (s *UrlshortenerServer) Load() {
 	// Swagger Files
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Browser sessions for non-public short links, below the reserved prefix "_"
+	// that is not a valid Shortlink name
+	router.GET("/_/login", s.HandleLogin)
+	router.GET("/_/callback", s.HandleLoginCallback)
+	router.GET("/_/logout", s.HandleLogout)
+	router.POST("/_/create", s.HandleCreateShortLinkForm)
+
 	// Short link Endpoint that triggers the redirect
 	router.GET("/:shortlink", s.HandleShortLink)
+	router.GET("/:shortlink/*rest", s.HandleShortLink)
+
+	// Unlocks password-protected short links
+	router.POST("/:shortlink", s.HandleShortLinkPassword)
+	router.POST("/:shortlink/*rest", s.HandleShortLinkPassword)
 
 	// ------------------------------------------------------------------------
 	// AUTHENTICATED ENDPOINTS
@@ -142,10 +203,17 @@ func (s *UrlshortenerServer) Load() {
 	// v1 API
 	v1 := api.Group("/v1")
 	v1.GET("/shortlink/", s.HandleListShortLink)
+	v1.POST("/shortlink/", s.HandleCreateRandomShortLink)
 	v1.GET("/shortlink/:shortlink", s.HandleGetShortLink)
 	v1.POST("/shortlink/:shortlink", s.HandleCreateShortLink)
 	v1.PUT("/shortlink/:shortlink", s.HandleUpdateShortLink)
 	v1.DELETE("/shortlink/:shortlink", s.HandleDeleteShortLink)
+
+	// Hierarchical short links such as sre/runbook
+	v1.GET("/shortlink/:shortlink/*path", s.HandleGetShortLink)
+	v1.POST("/shortlink/:shortlink/*path", s.HandleCreateShortLink)
+	v1.PUT("/shortlink/:shortlink/*path", s.HandleUpdateShortLink)
+	v1.DELETE("/shortlink/:shortlink/*path", s.HandleDeleteShortLink)
 }
 
 func (s *UrlshortenerServer) ServeAsync(addr string) {
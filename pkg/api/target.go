@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"golang.org/x/text/language"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+	"go.uber.org/zap"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+	"github.com/spechtlabs/urlshortener/pkg/rules"
+)
+
+// isTargetTemplate returns true if target contains placeholders
+func isTargetTemplate(target string) bool {
+	return v1alpha1.IsTargetTemplate(target)
+}
+
+// expandTarget fills the placeholders of a templated target with the path segments and query parameters
+// of the request. If the request carries no arguments at all, noArgsTarget is returned instead, if set.
+func expandTarget(target string, noArgsTarget string, segments []string, query url.Values) string {
+	if !isTargetTemplate(target) {
+		return target
+	}
+
+	if len(segments) == 0 && len(query) == 0 && noArgsTarget != "" {
+		return noArgsTarget
+	}
+
+	// Placeholders in the query or fragment of the target need query escaping, all others path escaping
+	queryStart := strings.IndexAny(target, "?#")
+	if queryStart < 0 {
+		queryStart = len(target)
+	}
+
+	var expanded strings.Builder
+	last := 0
+
+	for _, match := range v1alpha1.TargetPlaceholder.FindAllStringSubmatchIndex(target, -1) {
+		expanded.WriteString(target[last:match[0]])
+		last = match[1]
+
+		inQuery := match[0] > queryStart
+		escape := url.PathEscape
+		if inQuery {
+			escape = url.QueryEscape
+		}
+
+		name := target[match[2]:match[3]]
+
+		switch {
+		case name == "*" && inQuery:
+			expanded.WriteString(escape(strings.Join(segments, "/")))
+
+		case name == "*":
+			for idx, segment := range segments {
+				if idx > 0 {
+					expanded.WriteString("/")
+				}
+				expanded.WriteString(escape(segment))
+			}
+
+		case name[0] >= '0' && name[0] <= '9':
+			if idx, err := strconv.Atoi(name); err == nil && idx >= 1 && idx <= len(segments) {
+				expanded.WriteString(escape(segments[idx-1]))
+			}
+
+		default:
+			expanded.WriteString(escape(query.Get(name)))
+		}
+	}
+
+	expanded.WriteString(target[last:])
+	return expanded.String()
+}
+
+// splitPath splits the path following the shortlink name into its non-empty segments
+func splitPath(rest string) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(rest, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments
+}
+
+// applyPassthrough appends the remaining request path and query string to target,
+// depending on what the shortlink allows to be passed through
+func applyPassthrough(target string, passthrough v1alpha1.PassthroughSpec, rest string, rawQuery string) (string, error) {
+	if (!passthrough.Path || rest == "") && (!passthrough.Query || rawQuery == "") {
+		return target, nil
+	}
+
+	targetUrl, err := url.Parse(target)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to parse target")
+	}
+
+	if passthrough.Path && rest != "" {
+		// Clean the path as a rooted path first, so it cannot escape the target path with ../
+		cleaned := strings.TrimPrefix(path.Clean("/"+rest), "/")
+		if strings.HasSuffix(rest, "/") && cleaned != "" {
+			cleaned += "/"
+		}
+
+		targetUrl = targetUrl.JoinPath(cleaned)
+	}
+
+	if passthrough.Query && rawQuery != "" {
+		if targetUrl.RawQuery == "" {
+			targetUrl.RawQuery = rawQuery
+		} else {
+			targetUrl.RawQuery = strings.Join([]string{targetUrl.RawQuery, rawQuery}, "&")
+		}
+	}
+
+	return targetUrl.String(), nil
+}
+
+// variantCookieMaxAge is how long a visitor sticks to the variant it was assigned
+const variantCookieMaxAge = 30 * 24 * time.Hour
+
+// variantCookieName returns the name of the cookie holding the sticky variant of a shortlink
+func variantCookieName(shortlink *v1alpha1.Shortlink) string {
+	return "urlshortener_variant_" + shortlink.Name
+}
+
+// assignVariant picks the variant of the shortlink serving the request. Visitors of shortlinks with sticky variants
+// keep the variant they were assigned first, whichever name, alias or path they use to reach the shortlink.
+func (s *UrlshortenerServer) assignVariant(ct *gin.Context, shortlink *v1alpha1.Shortlink) *v1alpha1.TargetVariant {
+	sticky := ""
+	if shortlink.Spec.StickyVariants {
+		sticky, _ = ct.Cookie(variantCookieName(shortlink))
+	}
+
+	variant := pickVariant(shortlink.Spec.Variants, sticky)
+
+	// The cookie is named after the Shortlink object rather than the requested name, so it is sent along for every path
+	if variant != nil && shortlink.Spec.StickyVariants && sticky != variant.Name {
+		s.setCookie(ct, variantCookieName(shortlink), variant.Name, variantCookieMaxAge, "/")
+	}
+
+	return variant
+}
+
+// pickVariant picks one of the variants by weight. If sticky names a variant that can still receive traffic,
+// that variant is returned instead. Returns nil if no variant has a weight, which the validating webhook rejects.
+func pickVariant(variants []v1alpha1.TargetVariant, sticky string) *v1alpha1.TargetVariant {
+	total := 0
+	for idx, variant := range variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+
+		if sticky != "" && variant.Name == sticky {
+			return &variants[idx]
+		}
+
+		total += variant.Weight
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	pick := rand.IntN(total)
+	for idx, variant := range variants {
+		if variant.Weight <= 0 {
+			continue
+		}
+
+		if pick < variant.Weight {
+			return &variants[idx]
+		}
+
+		pick -= variant.Weight
+	}
+
+	return nil
+}
+
+// newRuleRequest collects the attributes of a request that targeting rules can match on
+func newRuleRequest(ct *gin.Context, now time.Time) rules.Request {
+	headers := make(map[string]string, len(ct.Request.Header))
+	for name, values := range ct.Request.Header {
+		if len(values) > 0 {
+			headers[strings.ToLower(name)] = values[0]
+		}
+	}
+
+	query := make(map[string]string)
+	for name, values := range ct.Request.URL.Query() {
+		if len(values) > 0 {
+			query[name] = values[0]
+		}
+	}
+
+	lang := ""
+	if tags, _, err := language.ParseAcceptLanguage(ct.GetHeader("Accept-Language")); err == nil && len(tags) > 0 {
+		base, _ := tags[0].Base()
+		lang = base.String()
+	}
+
+	return rules.Request{
+		Headers:   headers,
+		Language:  lang,
+		UserAgent: ct.Request.UserAgent(),
+		IP:        ct.ClientIP(),
+		Path:      ct.Request.URL.Path,
+		Query:     query,
+		Time:      now,
+	}
+}
+
+// matchRule returns the first targeting rule of the shortlink that matches the request, or nil if none matches.
+// Rules that fail to evaluate are skipped.
+func (s *UrlshortenerServer) matchRule(ctx context.Context, shortlink *v1alpha1.Shortlink, request rules.Request) *v1alpha1.TargetRule {
+	for idx, rule := range shortlink.Spec.Rules {
+		matched, err := s.rules.Match(rule.Expression, request)
+		if err != nil {
+			otelzap.L().WithError(err).Ctx(ctx).Warn("Failed to evaluate targeting rule",
+				zap.String("shortlink", shortlink.Name),
+				zap.String("expression", rule.Expression),
+			)
+			continue
+		}
+
+		if matched {
+			return &shortlink.Spec.Rules[idx]
+		}
+	}
+
+	return nil
+}
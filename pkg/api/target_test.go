@@ -0,0 +1,341 @@
+package api
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+func TestExpandTarget(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		noArgsTarget string
+		segments     []string
+		query        url.Values
+		want         string
+	}{
+		{
+			name:   "plain target is returned as is",
+			target: "https://example.com/docs",
+			want:   "https://example.com/docs",
+		},
+		{
+			name:     "numbered placeholders take the path segments",
+			target:   "https://github.com/{1}/{2}",
+			segments: []string{"spechtlabs", "urlshortener"},
+			want:     "https://github.com/spechtlabs/urlshortener",
+		},
+		{
+			name:     "missing segments expand to nothing",
+			target:   "https://github.com/{1}/{2}",
+			segments: []string{"spechtlabs"},
+			want:     "https://github.com/spechtlabs/",
+		},
+		{
+			name:     "wildcard takes all path segments",
+			target:   "https://example.com/docs/{*}",
+			segments: []string{"some", "page"},
+			want:     "https://example.com/docs/some/page",
+		},
+		{
+			name:     "path placeholders are path escaped",
+			target:   "https://example.com/{1}",
+			segments: []string{"a b?c#d"},
+			want:     "https://example.com/a%20b%3Fc%23d",
+		},
+		{
+			name:     "path segments cannot inject further segments",
+			target:   "https://example.com/{1}",
+			segments: []string{"../admin"},
+			want:     "https://example.com/..%2Fadmin",
+		},
+		{
+			name:     "query placeholders are query escaped",
+			target:   "https://example.com/search?q={1}",
+			segments: []string{"a b&c=d"},
+			want:     "https://example.com/search?q=a+b%26c%3Dd",
+		},
+		{
+			name:     "wildcard in the query joins the segments",
+			target:   "https://example.com/search?q={*}",
+			segments: []string{"a", "b"},
+			want:     "https://example.com/search?q=a%2Fb",
+		},
+		{
+			name:   "named placeholders take query parameters",
+			target: "https://jira.example.com/browse/{ticket}",
+			query:  url.Values{"ticket": {"OPS-1"}},
+			want:   "https://jira.example.com/browse/OPS-1",
+		},
+		{
+			name:         "no-args target is used without arguments",
+			target:       "https://jira.example.com/browse/{1}",
+			noArgsTarget: "https://jira.example.com",
+			want:         "https://jira.example.com",
+		},
+		{
+			name:         "no-args target is ignored with arguments",
+			target:       "https://jira.example.com/browse/{1}",
+			noArgsTarget: "https://jira.example.com",
+			segments:     []string{"OPS-1"},
+			want:         "https://jira.example.com/browse/OPS-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandTarget(tt.target, tt.noArgsTarget, tt.segments, tt.query); got != tt.want {
+				t.Errorf("expandTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPassthrough(t *testing.T) {
+	pathAndQuery := v1alpha1.PassthroughSpec{Path: true, Query: true}
+
+	tests := []struct {
+		name        string
+		target      string
+		passthrough v1alpha1.PassthroughSpec
+		rest        string
+		rawQuery    string
+		want        string
+	}{
+		{
+			name:     "nothing is passed through by default",
+			target:   "https://example.com/docs",
+			rest:     "some/page",
+			rawQuery: "a=b",
+			want:     "https://example.com/docs",
+		},
+		{
+			name:        "path is appended to the target path",
+			target:      "https://example.com/docs",
+			passthrough: v1alpha1.PassthroughSpec{Path: true},
+			rest:        "some/page",
+			rawQuery:    "a=b",
+			want:        "https://example.com/docs/some/page",
+		},
+		{
+			name:        "trailing slash of the path is kept",
+			target:      "https://example.com/docs",
+			passthrough: v1alpha1.PassthroughSpec{Path: true},
+			rest:        "some/",
+			want:        "https://example.com/docs/some/",
+		},
+		{
+			name:        "path cannot escape the target path",
+			target:      "https://example.com/docs",
+			passthrough: v1alpha1.PassthroughSpec{Path: true},
+			rest:        "../../admin",
+			want:        "https://example.com/docs/admin",
+		},
+		{
+			name:        "special characters of the path are escaped",
+			target:      "https://example.com/docs",
+			passthrough: v1alpha1.PassthroughSpec{Path: true},
+			rest:        "a b/c?d#e",
+			want:        "https://example.com/docs/a%20b/c%3Fd%23e",
+		},
+		{
+			name:        "query is passed through encoded",
+			target:      "https://example.com/search",
+			passthrough: v1alpha1.PassthroughSpec{Query: true},
+			rest:        "some/page",
+			rawQuery:    "q=a%20b%26c",
+			want:        "https://example.com/search?q=a%20b%26c",
+		},
+		{
+			name:        "query is appended to the query of the target",
+			target:      "https://example.com/search?lang=en",
+			passthrough: pathAndQuery,
+			rest:        "page",
+			rawQuery:    "q=x",
+			want:        "https://example.com/search/page?lang=en&q=x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPassthrough(tt.target, tt.passthrough, tt.rest, tt.rawQuery)
+			if err != nil {
+				t.Fatalf("applyPassthrough() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("applyPassthrough() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRuleRequestLanguage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		want           string
+	}{
+		{name: "no header", acceptLanguage: "", want: ""},
+		{name: "single language", acceptLanguage: "de", want: "de"},
+		{name: "region is dropped", acceptLanguage: "de-CH", want: "de"},
+		{name: "most preferred language wins", acceptLanguage: "fr;q=0.5, en-US, de;q=0.8", want: "en"},
+		{name: "invalid header", acceptLanguage: "en;q=invalid", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ct.Request = httptest.NewRequest("GET", "/docs?env=prod", nil)
+			ct.Request.Header.Set("X-Team", "sre")
+			if tt.acceptLanguage != "" {
+				ct.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+
+			request := newRuleRequest(ct, time.Now())
+
+			if request.Language != tt.want {
+				t.Errorf("Language = %q, want %q", request.Language, tt.want)
+			}
+
+			if request.Headers["x-team"] != "sre" || request.Query["env"] != "prod" || request.Path != "/docs" {
+				t.Errorf("unexpected request attributes %+v", request)
+			}
+		})
+	}
+}
+
+func TestNewRuleRequestIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		want           string
+	}{
+		{name: "forwarded headers are ignored without trusted proxies", trustedProxies: nil, want: "192.0.2.1"},
+		{name: "forwarded headers of untrusted proxies are ignored", trustedProxies: []string{"198.51.100.0/24"}, want: "192.0.2.1"},
+		{name: "forwarded headers of trusted proxies name the client", trustedProxies: []string{"192.0.2.1"}, want: "10.8.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, engine := gin.CreateTestContext(httptest.NewRecorder())
+			if err := engine.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatalf("SetTrustedProxies() error = %v", err)
+			}
+
+			ct.Request = httptest.NewRequest("GET", "/docs", nil)
+			ct.Request.Header.Set("X-Forwarded-For", "10.8.0.5")
+
+			if got := newRuleRequest(ct, time.Now()).IP; got != tt.want {
+				t.Errorf("IP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickVariant(t *testing.T) {
+	variants := []v1alpha1.TargetVariant{
+		{Name: "a", Target: "https://example.com/a", Weight: 1},
+		{Name: "b", Target: "https://example.com/b", Weight: 3},
+		{Name: "off", Target: "https://example.com/off", Weight: 0},
+	}
+
+	t.Run("variants are picked by weight", func(t *testing.T) {
+		picks := map[string]int{}
+		for range 10000 {
+			picks[pickVariant(variants, "").Name]++
+		}
+
+		if picks["off"] != 0 {
+			t.Errorf("variant without weight was picked %d times", picks["off"])
+		}
+
+		// b has three times the weight of a, so it should receive about 75% of the traffic
+		if share := float64(picks["b"]) / 10000; share < 0.72 || share > 0.78 {
+			t.Errorf("variant b received %.2f of the traffic, want about 0.75", share)
+		}
+	})
+
+	t.Run("sticky variant is kept", func(t *testing.T) {
+		for range 100 {
+			if got := pickVariant(variants, "a"); got.Name != "a" {
+				t.Fatalf("pickVariant() = %s, want sticky variant a", got.Name)
+			}
+		}
+	})
+
+	t.Run("sticky variant without weight is replaced", func(t *testing.T) {
+		if got := pickVariant(variants, "off"); got.Name == "off" {
+			t.Errorf("pickVariant() kept sticky variant without weight")
+		}
+	})
+
+	t.Run("unknown sticky variant is replaced", func(t *testing.T) {
+		if got := pickVariant(variants, "removed"); got == nil || got.Name == "removed" {
+			t.Errorf("pickVariant() = %v, want a weighted variant", got)
+		}
+	})
+
+	t.Run("no variant without weights", func(t *testing.T) {
+		if got := pickVariant(variants[2:], ""); got != nil {
+			t.Errorf("pickVariant() = %s, want nil", got.Name)
+		}
+	})
+}
+
+func TestAssignVariantSticksAcrossAliases(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := &UrlshortenerServer{}
+	shortlink := &v1alpha1.Shortlink{
+		Spec: v1alpha1.ShortlinkSpec{
+			Aliases:        []string{"handbook"},
+			StickyVariants: true,
+			Variants: []v1alpha1.TargetVariant{
+				{Name: "a", Target: "https://example.com/a", Weight: 1},
+				{Name: "b", Target: "https://example.com/b", Weight: 1},
+			},
+		},
+	}
+	shortlink.Name = "docs"
+
+	recorder := httptest.NewRecorder()
+	ct, _ := gin.CreateTestContext(recorder)
+	ct.Request = httptest.NewRequest("GET", "/docs", nil)
+
+	assigned := server.assignVariant(ct, shortlink)
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != variantCookieName(shortlink) || cookies[0].Value != assigned.Name {
+		t.Fatalf("cookies = %v, want the assigned variant %s", cookies, assigned.Name)
+	}
+
+	if cookies[0].Path != "/" {
+		t.Errorf("cookie path = %q, want / so it is sent for every alias", cookies[0].Path)
+	}
+
+	for range 20 {
+		recorder = httptest.NewRecorder()
+		ct, _ = gin.CreateTestContext(recorder)
+		ct.Request = httptest.NewRequest("GET", "/handbook", nil)
+		ct.Request.AddCookie(cookies[0])
+
+		if got := server.assignVariant(ct, shortlink); got.Name != assigned.Name {
+			t.Fatalf("assignVariant() = %s via alias, want sticky variant %s", got.Name, assigned.Name)
+		}
+
+		if len(recorder.Result().Cookies()) != 0 {
+			t.Errorf("the cookie of the sticky variant was set again")
+		}
+	}
+}
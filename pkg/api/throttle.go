@@ -0,0 +1,70 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// passwordAttemptWindow is the period in which the password attempts are counted
+	passwordAttemptWindow = 5 * time.Minute
+	// passwordAttemptsPerClient is how many passwords a single client IP may try within the window
+	passwordAttemptsPerClient = 10
+	// passwordAttemptsPerShortlink is how many passwords all clients together may try for a single shortlink within the window,
+	// so the password cannot be guessed by spreading the attempts over many addresses
+	passwordAttemptsPerShortlink = 50
+)
+
+// attemptLimiter counts attempts per key in fixed windows and rejects attempts beyond the maximum of a window
+type attemptLimiter struct {
+	maxAttempts int
+	window      time.Duration
+
+	mu        sync.Mutex
+	attempts  map[string]*attemptWindow
+	lastSweep time.Time
+}
+
+// attemptWindow holds the attempts of a key since the start of its current window
+type attemptWindow struct {
+	start time.Time
+	count int
+}
+
+func newAttemptLimiter(maxAttempts int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		maxAttempts: maxAttempts,
+		window:      window,
+		attempts:    make(map[string]*attemptWindow),
+	}
+}
+
+// Allow records an attempt for key and returns false if key already used up the attempts of its current window
+func (l *attemptLimiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget the keys whose window is over once per window, so the limiter does not grow with every client ever seen
+	if now.Sub(l.lastSweep) >= l.window {
+		for k, attempts := range l.attempts {
+			if now.Sub(attempts.start) >= l.window {
+				delete(l.attempts, k)
+			}
+		}
+
+		l.lastSweep = now
+	}
+
+	attempts, ok := l.attempts[key]
+	if !ok || now.Sub(attempts.start) >= l.window {
+		attempts = &attemptWindow{start: now}
+		l.attempts[key] = attempts
+	}
+
+	if attempts.count >= l.maxAttempts {
+		return false
+	}
+
+	attempts.count++
+	return true
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newAttemptLimiter(3, time.Minute)
+
+	for idx := range 3 {
+		if !limiter.Allow("10.0.0.1", now.Add(time.Duration(idx)*time.Second)) {
+			t.Fatalf("attempt %d was rejected", idx+1)
+		}
+	}
+
+	if limiter.Allow("10.0.0.1", now.Add(10*time.Second)) {
+		t.Errorf("attempt beyond the maximum was allowed")
+	}
+
+	if !limiter.Allow("10.0.0.2", now.Add(10*time.Second)) {
+		t.Errorf("attempt of another key was rejected")
+	}
+
+	if !limiter.Allow("10.0.0.1", now.Add(time.Minute)) {
+		t.Errorf("attempt in the next window was rejected")
+	}
+
+	// The window of 10.0.0.2 is over, so the sweep forgets it
+	limiter.Allow("10.0.0.1", now.Add(2*time.Minute))
+	if _, ok := limiter.attempts["10.0.0.2"]; ok {
+		t.Errorf("expired window was not swept")
+	}
+}
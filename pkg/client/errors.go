@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/sierrasoftworks/humane-errors-go"
 )
@@ -25,3 +26,31 @@ func NewNotAllowedError(username string, operation CRUDOperation, shortlinkName
 		"ensure you have the correct permissions to perform this operation on the ShortLink.",
 	)
 }
+
+func NewNameConflictError(name string, conflictingShortlinkName string) humane.Error {
+	return humane.New(
+		fmt.Sprintf("Name '%s' is already in use by ShortLink '%s'", name, conflictingShortlinkName),
+		"choose a different name or alias. Names are matched ignoring case as well as '-' and '_'.",
+	)
+}
+
+func NewAmbiguousNameError(name string, shortlinkNames []string) humane.Error {
+	return humane.New(
+		fmt.Sprintf("Name '%s' is ambiguous, it is used by the ShortLinks %s", name, strings.Join(shortlinkNames, ", ")),
+		"pass the domain the shortlink is served under to select one of them.",
+	)
+}
+
+func NewPolicyViolationError(target string, reason error) humane.Error {
+	return humane.New(
+		fmt.Sprintf("Target '%s' is not allowed by policy: %s", target, reason),
+		"choose a different target. Ask the administrators of the URL shortener if the target should be allowed.",
+	)
+}
+
+func NewInvalidRuleError(expression string, reason error) humane.Error {
+	return humane.New(
+		fmt.Sprintf("Expression '%s' is not a valid rule: %s", expression, reason),
+		"fix the expression of the targeting rule. Rules are CEL expressions that evaluate to a bool.",
+	)
+}
@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/spechtlabs/go-otel-utils/otelzap"
+)
+
+// InvocationCounter buffers Shortlink invocations in memory and periodically flushes
+// the merged counts into the Shortlink status. Every replica runs its own counter,
+// and each flush only ever adds the replica's local delta, so replicas never overwrite each other.
+type InvocationCounter struct {
+	client   *ShortlinkClient
+	interval time.Duration
+	tracer   trace.Tracer
+
+	mu     sync.Mutex
+	deltas map[types.NamespacedName]*InvocationDelta
+	// flushing holds the invocations that have been taken from deltas by a flush which has not finished writing them yet
+	flushing map[types.NamespacedName]int
+}
+
+// InvocationDelta holds the invocations of a Shortlink that have not been written to its status yet
+type InvocationDelta struct {
+	// Count is the number of invocations of the Shortlink
+	Count int
+
+	// Variants is the number of invocations per variant of the Shortlink
+	Variants map[string]int
+}
+
+// NewInvocationCounter creates a new InvocationCounter flushing every interval, which must be positive
+func NewInvocationCounter(client *ShortlinkClient, interval time.Duration) *InvocationCounter {
+	return &InvocationCounter{
+		client:   client,
+		interval: interval,
+		tracer:   otel.Tracer("urlshortener"),
+		deltas:   make(map[types.NamespacedName]*InvocationDelta),
+		flushing: make(map[types.NamespacedName]int),
+	}
+}
+
+// Increment records a single invocation of the given Shortlink.
+// If the invocation was served by a variant of the Shortlink, variant holds its name, otherwise it is empty.
+func (c *InvocationCounter) Increment(nameNamespaced types.NamespacedName, variant string) {
+	delta := &InvocationDelta{Count: 1}
+	if variant != "" {
+		delta.Variants = map[string]int{variant: 1}
+	}
+
+	c.add(nameNamespaced, delta)
+}
+
+// Pending returns the number of invocations of the given Shortlink that have not been written to its status yet,
+// including those a running flush is still writing
+func (c *InvocationCounter) Pending(nameNamespaced types.NamespacedName) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.flushing[nameNamespaced]
+	if delta, ok := c.deltas[nameNamespaced]; ok {
+		pending += delta.Count
+	}
+
+	return pending
+}
+
+// Start flushes the buffered invocations every interval until the context is done,
+// and flushes one last time before returning. It implements manager.Runnable.
+func (c *InvocationCounter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush(ctx)
+
+		case <-ctx.Done():
+			// ctx is already cancelled, give the final flush its own deadline
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			c.Flush(shutdownCtx)
+			cancel()
+
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Every replica serves
+// redirects and therefore has to flush its own invocations.
+func (c *InvocationCounter) NeedLeaderElection() bool {
+	return false
+}
+
+// Flush writes all buffered invocations to the Shortlink status.
+// Deltas that fail to be written are kept and retried on the next flush.
+func (c *InvocationCounter) Flush(ct context.Context) {
+	c.mu.Lock()
+	deltas := c.deltas
+	c.deltas = make(map[types.NamespacedName]*InvocationDelta, len(deltas))
+	for nameNamespaced, delta := range deltas {
+		c.flushing[nameNamespaced] += delta.Count
+	}
+	c.mu.Unlock()
+
+	if len(deltas) == 0 {
+		return
+	}
+
+	ctx, span := c.tracer.Start(ct, "InvocationCounter.Flush", trace.WithAttributes(attribute.Int("shortlinks", len(deltas))))
+	defer span.End()
+
+	for nameNamespaced, delta := range deltas {
+		err := c.client.AddInvocationCount(ctx, nameNamespaced, delta)
+
+		// The Shortlink is gone, there is nothing left to count
+		if err == nil || k8serrors.IsNotFound(err) {
+			c.finishFlush(nameNamespaced, delta, false)
+			continue
+		}
+
+		otelzap.L().WithError(err).Ctx(ctx).Error("Failed to flush invocation count",
+			zap.String("shortlink", nameNamespaced.String()),
+			zap.Int("delta", delta.Count),
+		)
+
+		c.finishFlush(nameNamespaced, delta, true)
+	}
+}
+
+// finishFlush removes a delta from the invocations being flushed, and puts it back into the buffer if retry is set.
+// Both happen at once, so Pending never misses the delta in between.
+func (c *InvocationCounter) finishFlush(nameNamespaced types.NamespacedName, delta *InvocationDelta, retry bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flushing[nameNamespaced] -= delta.Count
+	if c.flushing[nameNamespaced] <= 0 {
+		delete(c.flushing, nameNamespaced)
+	}
+
+	if retry {
+		c.addLocked(nameNamespaced, delta)
+	}
+}
+
+func (c *InvocationCounter) add(nameNamespaced types.NamespacedName, delta *InvocationDelta) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addLocked(nameNamespaced, delta)
+}
+
+// addLocked merges delta into the buffer, c.mu must be held
+func (c *InvocationCounter) addLocked(nameNamespaced types.NamespacedName, delta *InvocationDelta) {
+	merged, ok := c.deltas[nameNamespaced]
+	if !ok {
+		merged = &InvocationDelta{}
+		c.deltas[nameNamespaced] = merged
+	}
+
+	merged.Count += delta.Count
+	for variant, count := range delta.Variants {
+		if merged.Variants == nil {
+			merged.Variants = make(map[string]int, len(delta.Variants))
+		}
+		merged.Variants[variant] += count
+	}
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+func TestInvocationCounterPendingDuringFlush(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+
+	shortlink := &v1alpha1.Shortlink{ObjectMeta: metav1.ObjectMeta{Name: "docs", Namespace: "default"}}
+	nameNamespaced := types.NamespacedName{Name: shortlink.Name, Namespace: shortlink.Namespace}
+
+	updating := make(chan struct{})
+	release := make(chan error)
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(shortlink).
+		WithStatusSubresource(shortlink).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+				updating <- struct{}{}
+				if err := <-release; err != nil {
+					return err
+				}
+
+				return c.SubResource(subResource).Update(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	counter := NewInvocationCounter(NewShortlinkClient(k8sClient), time.Minute)
+
+	// flush runs a flush in the background, waits until it writes the status and checks the pending invocations meanwhile
+	flush := func(writeErr error, wantPending int) {
+		t.Helper()
+
+		done := make(chan struct{})
+		go func() {
+			counter.Flush(context.Background())
+			close(done)
+		}()
+
+		<-updating
+		counter.Increment(nameNamespaced, "")
+		if got := counter.Pending(nameNamespaced); got != wantPending {
+			t.Errorf("Pending() during flush = %d, want %d", got, wantPending)
+		}
+
+		release <- writeErr
+		<-done
+	}
+
+	counter.Increment(nameNamespaced, "")
+	counter.Increment(nameNamespaced, "")
+
+	// The failed flush puts its two invocations back next to the one counted meanwhile
+	flush(errors.New("apiserver unavailable"), 3)
+	if got := counter.Pending(nameNamespaced); got != 3 {
+		t.Errorf("Pending() after failed flush = %d, want 3", got)
+	}
+
+	// The successful flush writes the three invocations, only the one counted meanwhile stays pending
+	flush(nil, 4)
+	if got := counter.Pending(nameNamespaced); got != 1 {
+		t.Errorf("Pending() after flush = %d, want 1", got)
+	}
+
+	written := &v1alpha1.Shortlink{}
+	if err := k8sClient.Get(context.Background(), nameNamespaced, written); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if written.Status.Count != 3 {
+		t.Errorf("Status.Count = %d, want 3", written.Status.Count)
+	}
+}
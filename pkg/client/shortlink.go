@@ -10,29 +10,57 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	v1alpha1 "github.com/spechtlabs/urlshortener/api/v1alpha1"
+	"github.com/spechtlabs/urlshortener/pkg/slug"
 )
 
 // ShortlinkClient is a Kubernetes client for easy CRUD operations
 type ShortlinkClient struct {
 	client client.Client
+	reader client.Reader
 	tracer trace.Tracer
 }
 
 // NewShortlinkClient creates a new shortlink Client
 func NewShortlinkClient(client client.Client) *ShortlinkClient {
+	return NewShortlinkClientWithReader(client, client)
+}
+
+// NewShortlinkClientWithReader creates a new shortlink Client that reads Shortlinks through reader
+// before it updates their status, e.g. the API reader of the manager that bypasses the cache
+func NewShortlinkClientWithReader(client client.Client, reader client.Reader) *ShortlinkClient {
 	return &ShortlinkClient{
 		client: client,
+		reader: reader,
 		tracer: otel.Tracer("urlshortener"),
 	}
 }
 
-// Get returns a ShortLink in the current namespace
-func (c *ShortlinkClient) Get(ct context.Context, name string) (*v1alpha1.Shortlink, error) {
-	ctx, span := c.tracer.Start(ct, "ShortlinkClient.Get", trace.WithAttributes(attribute.String("name", name)))
+// SlugIndexField is the field index of Shortlinks by their slug
+const SlugIndexField = "spec.slug"
+
+// SetupSlugIndex registers the slug index of Shortlinks with the field indexer of the manager
+func SetupSlugIndex(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &v1alpha1.Shortlink{}, SlugIndexField, func(obj client.Object) []string {
+		shortlink, ok := obj.(*v1alpha1.Shortlink)
+		if !ok || shortlink.Spec.Slug == "" {
+			return nil
+		}
+
+		return []string{shortlink.Spec.Slug}
+	})
+}
+
+// Get returns the ShortLink with the given slug in the current namespace, served under the given domain.
+// The slug is mapped to the object name it is stored under, or looked up in the slug index. An empty domain
+// matches every ShortLink, so it returns an error if the slug is used by ShortLinks under several domains.
+func (c *ShortlinkClient) Get(ct context.Context, name string, domain string) (*v1alpha1.Shortlink, error) {
+	ctx, span := c.tracer.Start(ct, "ShortlinkClient.Get", trace.WithAttributes(attribute.String("name", name), attribute.String("domain", domain)))
 	defer span.End()
 
 	// try to read the namespace from /var/run
@@ -42,7 +70,49 @@ func (c *ShortlinkClient) Get(ct context.Context, name string) (*v1alpha1.Shortl
 		return nil, errors.Wrap(err, "Unable to read current namespace")
 	}
 
-	return c.GetNamespaced(ctx, types.NamespacedName{Name: name, Namespace: string(namespace)})
+	// Shortlinks bound to domains are stored under their name followed by their first domain, e.g. docs.go.corp
+	objectNames := []string{slug.ObjectName(name)}
+	if domain != "" {
+		objectNames = append([]string{slug.ObjectName(name + "." + v1alpha1.NormalizeDomain(domain))}, objectNames...)
+	}
+
+	for _, objectName := range objectNames {
+		shortlink, err := c.GetNamespaced(ctx, types.NamespacedName{Name: objectName, Namespace: string(namespace)})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+
+		if err == nil && (domain == "" || shortlink.ServesDomain(domain)) {
+			return shortlink, nil
+		}
+	}
+
+	// The Shortlink may have been created under a different object name, e.g. with kubectl
+	shortlinks := &v1alpha1.ShortlinkList{}
+	if err := c.client.List(ctx, shortlinks, client.InNamespace(string(namespace)), client.MatchingFields{SlugIndexField: name}); err != nil {
+		span.RecordError(err)
+		return nil, errors.Wrap(err, "Unable to look up slug")
+	}
+
+	matches := make([]string, 0, len(shortlinks.Items))
+	var match *v1alpha1.Shortlink
+	for idx := range shortlinks.Items {
+		if domain == "" || shortlinks.Items[idx].ServesDomain(domain) {
+			match = &shortlinks.Items[idx]
+			matches = append(matches, match.Name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, k8serrors.NewNotFound(v1alpha1.GroupVersion.WithResource("shortlinks").GroupResource(), name)
+	case 1:
+		return match, nil
+	default:
+		err := NewAmbiguousNameError(name, matches)
+		span.RecordError(err)
+		return nil, err
+	}
 }
 
 // GetNameNamespace returns a Shortlink for a given name in a given namespace
@@ -108,6 +178,11 @@ func (c *ShortlinkClient) Update(ct context.Context, shortlink *v1alpha1.Shortli
 	ctx, span := c.tracer.Start(ct, "ShortlinkClient.Update", trace.WithAttributes(attribute.String("shortlink", shortlink.Name), attribute.String("namespace", shortlink.Namespace)))
 	defer span.End()
 
+	if err := c.checkNameConflicts(ctx, shortlink); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
 	if err := c.client.Update(ctx, shortlink); err != nil {
 		span.RecordError(err)
 		return err
@@ -128,13 +203,38 @@ func (c *ShortlinkClient) UpdateStatus(ct context.Context, shortlink *v1alpha1.S
 	return err
 }
 
-func (c *ShortlinkClient) IncrementInvocationCount(ct context.Context, shortlink *v1alpha1.Shortlink) error {
-	ctx, span := c.tracer.Start(ct, "ShortlinkClient.IncrementInvocationCount", trace.WithAttributes(attribute.String("shortlink", shortlink.Name), attribute.String("namespace", shortlink.Namespace)))
+// AddInvocationCount adds delta to the invocation count and the per-variant counts of a Shortlink.
+// The Shortlink is re-read through the reader of the client on every attempt, so concurrent status writers only cause
+// a retry instead of lost counts. The reader should bypass the cache, otherwise every retry may see the same stale version.
+func (c *ShortlinkClient) AddInvocationCount(ct context.Context, nameNamespaced types.NamespacedName, delta *InvocationDelta) error {
+	ctx, span := c.tracer.Start(
+		ct, "ShortlinkClient.AddInvocationCount",
+		trace.WithAttributes(
+			attribute.String("shortlink", nameNamespaced.Name),
+			attribute.String("namespace", nameNamespaced.Namespace),
+			attribute.Int("delta", delta.Count),
+		),
+	)
 	defer span.End()
 
-	shortlink.Status.Count = shortlink.Status.Count + 1
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		shortlink := &v1alpha1.Shortlink{}
+		if err := c.reader.Get(ctx, nameNamespaced, shortlink); err != nil {
+			return err
+		}
 
-	if err := c.client.Status().Update(ctx, shortlink); err != nil {
+		shortlink.Status.Count = shortlink.Status.Count + delta.Count
+
+		for variant, count := range delta.Variants {
+			if shortlink.Status.VariantCounts == nil {
+				shortlink.Status.VariantCounts = make(map[string]int, len(delta.Variants))
+			}
+			shortlink.Status.VariantCounts[variant] += count
+		}
+
+		return c.client.Status().Update(ctx, shortlink)
+	})
+	if err != nil {
 		span.RecordError(err)
 		return err
 	}
@@ -169,6 +269,11 @@ func (c *ShortlinkClient) Create(ct context.Context, shortlink *v1alpha1.Shortli
 		shortlink.Namespace = string(namespace)
 	}
 
+	if err := c.checkNameConflicts(ctx, shortlink); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
 	// if not exists, create a new one
 	if err := c.client.Create(ctx, shortlink); err != nil {
 		span.RecordError(err)
@@ -177,3 +282,25 @@ func (c *ShortlinkClient) Create(ct context.Context, shortlink *v1alpha1.Shortli
 
 	return nil
 }
+
+// checkNameConflicts returns an error if the name or one of the aliases of the shortlink
+// is already used by another Shortlink in the same namespace
+func (c *ShortlinkClient) checkNameConflicts(ctx context.Context, shortlink *v1alpha1.Shortlink) error {
+	shortlinks, err := c.ListNamespaced(ctx, shortlink.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "Unable to list shortlinks")
+	}
+
+	for idx := range shortlinks.Items {
+		other := &shortlinks.Items[idx]
+		if other.Name == shortlink.Name {
+			continue
+		}
+
+		if name := shortlink.ConflictingName(other); name != "" {
+			return NewNameConflictError(name, other.Name)
+		}
+	}
+
+	return nil
+}
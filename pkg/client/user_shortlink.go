@@ -4,25 +4,41 @@ import (
 	"context"
 
 	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+	"github.com/spechtlabs/urlshortener/pkg/policy"
+	"github.com/spechtlabs/urlshortener/pkg/rules"
 	"go.opentelemetry.io/otel"
 
 	"github.com/pkg/errors"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// User is the GitHub user a request is made on behalf of
+type User struct {
+	// Login identifies the user and is the owner of the shortlinks the user creates
+	Login string
+	// Name is the display name of the user, which owns the shortlinks created before users were identified by their login
+	Name string
+}
+
 type UserShortLinkClient struct {
 	tracer trace.Tracer
 	client *ShortlinkClient
+	policy *policy.Policy
+	rules  *rules.Evaluator
 }
 
-func NewUserShortLinkClient(client *ShortlinkClient) *UserShortLinkClient {
+// NewUserShortLinkClient returns a new UserShortLinkClient that checks the targets of the shortlinks
+// users create or update against the policy, and compiles their targeting rules with the evaluator
+func NewUserShortLinkClient(client *ShortlinkClient, policy *policy.Policy, rules *rules.Evaluator) *UserShortLinkClient {
 	return &UserShortLinkClient{
 		tracer: otel.Tracer("urlshortener"),
 		client: client,
+		policy: policy,
+		rules:  rules,
 	}
 }
 
-func (c *UserShortLinkClient) List(ct context.Context, username string) (*v1alpha1.ShortlinkList, error) {
+func (c *UserShortLinkClient) List(ct context.Context, user User) (*v1alpha1.ShortlinkList, error) {
 	ctx, span := c.tracer.Start(ct, "UserShortLinkClient.List")
 	defer span.End()
 
@@ -38,65 +54,121 @@ func (c *UserShortLinkClient) List(ct context.Context, username string) (*v1alph
 	}
 
 	for _, shortLink := range list.Items {
-		if shortLink.IsOwnedBy(username) {
+		if shortLink.IsOwnedBy(user.Login, user.Name) {
 			userShortlinkList.Items = append(userShortlinkList.Items, shortLink)
 		}
 	}
 
 	if len(userShortlinkList.Items) == 0 {
-		return nil, NewNotAllowedError(username, ReadOperation, "all shortlinks")
+		return nil, NewNotAllowedError(user.Login, ReadOperation, "all shortlinks")
 	}
 
 	return &userShortlinkList, nil
 }
 
-func (c *UserShortLinkClient) Get(ct context.Context, username string, name string) (*v1alpha1.Shortlink, error) {
+// Get returns the shortlink with the given name served under the given domain, if the user owns it.
+// An empty domain matches shortlinks under every domain.
+func (c *UserShortLinkClient) Get(ct context.Context, user User, name string, domain string) (*v1alpha1.Shortlink, error) {
 	ctx, span := c.tracer.Start(ct, "UserShortLinkClient.Get")
 	defer span.End()
 
-	shortLink, err := c.client.Get(ctx, name)
+	shortLink, err := c.client.Get(ctx, name, domain)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to get shortlink")
 	}
 
-	if !shortLink.IsOwnedBy(username) {
-		return nil, NewNotAllowedError(username, ReadOperation, shortLink.Name)
+	if !shortLink.IsOwnedBy(user.Login, user.Name) {
+		return nil, NewNotAllowedError(user.Login, ReadOperation, shortLink.Name)
 	}
 
 	return shortLink, nil
 }
 
-func (c *UserShortLinkClient) Create(ct context.Context, username string, shortLink *v1alpha1.Shortlink) error {
+func (c *UserShortLinkClient) Create(ct context.Context, user User, shortLink *v1alpha1.Shortlink) error {
 	ctx, span := c.tracer.Start(ct, "UserShortLinkClient.Create")
 	defer span.End()
 
-	shortLink.Spec.Owner = username
+	if err := c.checkPolicy(shortLink); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := c.checkRules(shortLink); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	shortLink.Spec.Owner = user.Login
+	if shortLink.Labels == nil {
+		shortLink.Labels = map[string]string{}
+	}
+	shortLink.Labels[v1alpha1.OwnedByLoginLabel] = "true"
 	return c.client.Create(ctx, shortLink)
 }
 
-func (c *UserShortLinkClient) Update(ct context.Context, username string, shortLink *v1alpha1.Shortlink) error {
+func (c *UserShortLinkClient) Update(ct context.Context, user User, shortLink *v1alpha1.Shortlink) error {
 	ctx, span := c.tracer.Start(ct, "UserShortLinkClient.Update")
 	defer span.End()
 
-	if !shortLink.IsOwnedBy(username) {
-		return NewNotAllowedError(username, UpdateOperation, shortLink.Name)
+	if !shortLink.IsOwnedBy(user.Login, user.Name) {
+		return NewNotAllowedError(user.Login, UpdateOperation, shortLink.Name)
+	}
+
+	if err := c.checkPolicy(shortLink); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	if err := c.checkRules(shortLink); err != nil {
+		span.RecordError(err)
+		return err
 	}
 
 	if err := c.client.Update(ctx, shortLink); err != nil {
 		return err
 	}
 
-	shortLink.Status.ChangedBy = username
+	shortLink.Status.ChangedBy = user.Login
 	return c.client.UpdateStatus(ctx, shortLink)
 }
 
-func (c *UserShortLinkClient) Delete(ct context.Context, username string, shortLink *v1alpha1.Shortlink) error {
+func (c *UserShortLinkClient) Delete(ct context.Context, user User, shortLink *v1alpha1.Shortlink) error {
 	ctx, span := c.tracer.Start(ct, "UserShortLinkClient.Update")
 	defer span.End()
 
-	if !shortLink.IsOwnedBy(username) {
-		return NewNotAllowedError(username, DeleteOperation, shortLink.Name)
+	if !shortLink.IsOwnedBy(user.Login, user.Name) {
+		return NewNotAllowedError(user.Login, DeleteOperation, shortLink.Name)
 	}
 
 	return c.client.Delete(ctx, shortLink)
 }
+
+// checkPolicy returns an error if one of the targets of the shortlink is not allowed by the policy
+func (c *UserShortLinkClient) checkPolicy(shortLink *v1alpha1.Shortlink) error {
+	if c.policy == nil {
+		return nil
+	}
+
+	for _, target := range policy.Targets(shortLink) {
+		if err := c.policy.CheckTarget(target); err != nil {
+			return NewPolicyViolationError(target, err)
+		}
+	}
+
+	return nil
+}
+
+// checkRules returns an error if the expression of one of the targeting rules of the shortlink does not compile
+func (c *UserShortLinkClient) checkRules(shortLink *v1alpha1.Shortlink) error {
+	if c.rules == nil {
+		return nil
+	}
+
+	for _, rule := range shortLink.Spec.Rules {
+		if _, err := c.rules.Compile(rule.Expression); err != nil {
+			return NewInvalidRuleError(rule.Expression, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,437 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/idna"
+	"sigs.k8s.io/yaml"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+// ConfigMapKey is the key of the ConfigMap holding the policy
+const ConfigMapKey = "policy.yaml"
+
+// placeholderHost replaces the placeholders of templated targets, so they can be parsed as URLs
+const placeholderHost = "urlshortener-placeholder"
+
+// DefaultBlockedSchemes are the schemes targets must not use if the policy does not configure them
+var DefaultBlockedSchemes = []string{"javascript", "data", "file", "vbscript"}
+
+// targetScheme matches the scheme of a target, e.g. javascript: in javascript:alert(1)
+var targetScheme = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9+.-]*):`)
+
+// ipv4Number matches the decimal, octal and hex numbers browsers accept as parts of IPv4 addresses
+var ipv4Number = regexp.MustCompile(`^([0-9]+|0[xX][0-9A-Fa-f]*)$`)
+
+// Config configures which targets shortlinks may point to
+type Config struct {
+	// AllowedDomains are the only domains targets may point to, such as example.com, or *.example.com for all of its subdomains.
+	// If empty, targets may point to every domain that is not blocked.
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+
+	// BlockedDomains are domains targets must not point to, using the same wildcards as AllowedDomains.
+	// A blocked domain is rejected even if it is allowed as well.
+	BlockedDomains []string `json:"blockedDomains,omitempty"`
+
+	// BlockedSchemes are the URL schemes targets must not use. If unset, DefaultBlockedSchemes are blocked.
+	BlockedSchemes []string `json:"blockedSchemes,omitempty"`
+
+	// AllowPrivateIPs allows targets pointing to localhost or to loopback, private, carrier-grade NAT and link-local IP addresses
+	AllowPrivateIPs bool `json:"allowPrivateIPs,omitempty"`
+
+	// AllowHomographs allows domains that mix scripts, such as Latin and Cyrillic letters, or that are written
+	// entirely in Cyrillic or Greek letters under a Latin top-level domain, which is used to imitate other domains
+	AllowHomographs bool `json:"allowHomographs,omitempty"`
+}
+
+// Policy checks the targets of Shortlinks before they are written
+type Policy struct {
+	allowedDomains []string
+	blockedDomains []string
+	blockedSchemes []string
+	config         Config
+}
+
+// New creates a new Policy from the given configuration
+func New(config Config) (*Policy, error) {
+	allowedDomains, err := normalizeDomains(config.AllowedDomains)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid allowed domain")
+	}
+
+	blockedDomains, err := normalizeDomains(config.BlockedDomains)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid blocked domain")
+	}
+
+	blockedSchemes := config.BlockedSchemes
+	if blockedSchemes == nil {
+		blockedSchemes = DefaultBlockedSchemes
+	}
+
+	policy := &Policy{
+		allowedDomains: allowedDomains,
+		blockedDomains: blockedDomains,
+		blockedSchemes: make([]string, 0, len(blockedSchemes)),
+		config:         config,
+	}
+
+	for _, scheme := range blockedSchemes {
+		policy.blockedSchemes = append(policy.blockedSchemes, strings.ToLower(strings.TrimSuffix(scheme, ":")))
+	}
+
+	return policy, nil
+}
+
+// Load creates a new Policy from the YAML or JSON configuration in the given file
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to read policy file")
+	}
+
+	return parse(data)
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+
+// LoadConfigMap creates a new Policy from the YAML or JSON configuration stored under ConfigMapKey in the given ConfigMap
+func LoadConfigMap(ctx context.Context, reader client.Reader, key types.NamespacedName) (*Policy, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, key, configMap); err != nil {
+		return nil, errors.Wrapf(err, "Unable to get policy ConfigMap %s", key)
+	}
+
+	data, ok := configMap.Data[ConfigMapKey]
+	if !ok {
+		return nil, fmt.Errorf("policy ConfigMap %s has no key %s", key, ConfigMapKey)
+	}
+
+	return parse([]byte(data))
+}
+
+func parse(data []byte) (*Policy, error) {
+	config := Config{}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, errors.Wrap(err, "Unable to parse policy")
+	}
+
+	return New(config)
+}
+
+// CheckTarget returns an error describing why the target violates the policy, or nil if it is allowed
+func (p *Policy) CheckTarget(target string) error {
+	// Browsers ignore tabs and newlines in URLs, so java\tscript: is javascript:
+	cleaned := strings.TrimSpace(strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(target))
+
+	if match := targetScheme.FindStringSubmatch(cleaned); match != nil && slices.Contains(p.blockedSchemes, strings.ToLower(match[1])) {
+		return fmt.Errorf("scheme %s is not allowed", strings.ToLower(match[1]))
+	}
+
+	// Targets without scheme are redirected to via http, just like the redirect handler does
+	if !strings.HasPrefix(cleaned, "http") {
+		cleaned = "http://" + cleaned
+	}
+
+	parsed, err := url.Parse(v1alpha1.TargetPlaceholder.ReplaceAllString(cleaned, placeholderHost))
+	if err != nil {
+		return errors.Wrap(err, "target is not a valid URL")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("target has no host")
+	}
+
+	// The host of a templated target is only known once it is requested, so it could point anywhere
+	if strings.Contains(host, placeholderHost) {
+		return fmt.Errorf("host of the target must not contain placeholders")
+	}
+
+	ip, err := parseHostIP(host)
+	if err != nil {
+		return err
+	}
+
+	if !p.config.AllowPrivateIPs && (isLocalhost(host) || (ip != nil && IsPrivateIP(ip))) {
+		return fmt.Errorf("private host %s is not allowed", host)
+	}
+
+	if !p.config.AllowHomographs && ip == nil {
+		if err := checkHomograph(host); err != nil {
+			return err
+		}
+	}
+
+	// Compare the ASCII form of the domain, so lists can use either form of internationalized domains
+	asciiHost := host
+	if ip != nil {
+		asciiHost = ip.String()
+	} else if asciiHost, err = idna.Lookup.ToASCII(host); err != nil {
+		return errors.Wrapf(err, "invalid domain %s", host)
+	}
+
+	if matchesAny(asciiHost, p.blockedDomains) {
+		return fmt.Errorf("domain %s is blocked", host)
+	}
+
+	if len(p.allowedDomains) > 0 && !matchesAny(asciiHost, p.allowedDomains) {
+		return fmt.Errorf("domain %s is not allowed", host)
+	}
+
+	return nil
+}
+
+// Targets returns all targets a Shortlink may redirect to
+func Targets(shortlink *v1alpha1.Shortlink) []string {
+	targets := []string{shortlink.Spec.Target}
+
+	if shortlink.Spec.NoArgsTarget != "" {
+		targets = append(targets, shortlink.Spec.NoArgsTarget)
+	}
+
+	if shortlink.Spec.FallbackTarget != "" {
+		targets = append(targets, shortlink.Spec.FallbackTarget)
+	}
+
+	for _, entry := range shortlink.Spec.Schedule {
+		targets = append(targets, entry.Target)
+	}
+
+	for _, rule := range shortlink.Spec.Rules {
+		targets = append(targets, rule.Target)
+	}
+
+	for _, variant := range shortlink.Spec.Variants {
+		targets = append(targets, variant.Target)
+	}
+
+	return targets
+}
+
+// isLocalhost returns true for localhost and its subdomains, which always resolve to a loopback address
+func isLocalhost(host string) bool {
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// privateNetworks are the networks IsPrivateIP reports beyond the loopback, private, link-local and unspecified addresses
+var privateNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),     // "this network", which reaches the local host
+	mustParseCIDR("100.64.0.0/10"), // carrier-grade NAT, often used for cluster and VPN networks
+}
+
+// nat64Prefix is the well-known prefix of IPv6 addresses embedding an IPv4 address, e.g. 64:ff9b::7f00:1 for 127.0.0.1
+var nat64Prefix = mustParseCIDR("64:ff9b::/96")
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// IsPrivateIP returns true for loopback, private, carrier-grade NAT, link-local and unspecified IP addresses,
+// including IPv4 addresses embedded in IPv6 addresses such as ::ffff:127.0.0.1
+func IsPrivateIP(ip net.IP) bool {
+	if nat64Prefix.Contains(ip) {
+		ip = net.IP(ip.To16()[12:])
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
+		return true
+	}
+
+	return slices.ContainsFunc(privateNetworks, func(network *net.IPNet) bool { return network.Contains(ip) })
+}
+
+// parseHostIP returns the IP address of a host, or nil if the host is a domain. Besides the usual notations it parses
+// the IPv4 notations browsers accept, so 2130706433, 127.1, 0177.0.0.1 and 0x7f.0.0.1 are all read as 127.0.0.1.
+// Hosts that browsers read as IPv4 address but that are out of range return an error.
+// Domains are not resolved, as their addresses may change at any time after the check.
+func parseHostIP(host string) (net.IP, error) {
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		return ip, nil
+	}
+
+	// Browsers read a host as IPv4 address if its last label is a number, whose parts may be decimal, octal or hex
+	parts := strings.Split(host, ".")
+	if !ipv4Number.MatchString(parts[len(parts)-1]) {
+		return nil, nil
+	}
+
+	if len(parts) > 4 {
+		return nil, fmt.Errorf("invalid IPv4 address %s", host)
+	}
+
+	var address uint64
+	for idx, part := range parts {
+		value, err := parseIPv4Part(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPv4 address %s", host)
+		}
+
+		// All parts but the last are single bytes, the last one fills the remaining bytes, e.g. 127.1 is 127.0.0.1
+		if idx < len(parts)-1 {
+			if value > 255 {
+				return nil, fmt.Errorf("invalid IPv4 address %s", host)
+			}
+
+			address |= value << (8 * (3 - idx))
+		} else {
+			if value >= 1<<(8*(4-idx)) {
+				return nil, fmt.Errorf("invalid IPv4 address %s", host)
+			}
+
+			address |= value
+		}
+	}
+
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address)), nil
+}
+
+// parseIPv4Part parses a part of an IPv4 address as browsers do: hex with a 0x prefix, octal with a leading 0, otherwise decimal
+func parseIPv4Part(part string) (uint64, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x") || strings.HasPrefix(part, "0X"):
+		part, base = part[2:], 16
+		if part == "" {
+			return 0, nil
+		}
+
+	case len(part) > 1 && part[0] == '0':
+		part, base = part[1:], 8
+	}
+
+	return strconv.ParseUint(part, base, 64)
+}
+
+// homographScripts are the scripts whose letters are checked for imitating other domains
+var homographScripts = map[string]*unicode.RangeTable{
+	"Latin":    unicode.Latin,
+	"Cyrillic": unicode.Cyrillic,
+	"Greek":    unicode.Greek,
+	"Armenian": unicode.Armenian,
+	"Arabic":   unicode.Arabic,
+	"Hebrew":   unicode.Hebrew,
+	"Han":      unicode.Han,
+	"Hiragana": unicode.Hiragana,
+	"Katakana": unicode.Katakana,
+	"Hangul":   unicode.Hangul,
+	"Thai":     unicode.Thai,
+}
+
+// checkHomograph returns an error if a label of the domain mixes scripts, or if a label is written entirely
+// in Cyrillic or Greek letters under a Latin top-level domain, e.g. аpple.com with a Cyrillic а
+func checkHomograph(host string) error {
+	unicodeHost, err := idna.Lookup.ToUnicode(host)
+	if err != nil {
+		return errors.Wrapf(err, "invalid domain %s", host)
+	}
+
+	labels := strings.Split(unicodeHost, ".")
+	tldScripts := labelScripts(labels[len(labels)-1])
+
+	for _, label := range labels {
+		scripts := labelScripts(label)
+
+		// Japanese mixes Han, Hiragana and Katakana, so these may be combined
+		if len(scripts) > 1 && !isJapanese(scripts) {
+			return fmt.Errorf("domain %s mixes the scripts %s", unicodeHost, strings.Join(scripts, ", "))
+		}
+
+		if len(scripts) == 1 && (scripts[0] == "Cyrillic" || scripts[0] == "Greek") && slices.Equal(tldScripts, []string{"Latin"}) {
+			return fmt.Errorf("domain %s is written in %s letters under a Latin top-level domain", unicodeHost, scripts[0])
+		}
+	}
+
+	return nil
+}
+
+// labelScripts returns the sorted scripts of the letters of a domain label
+func labelScripts(label string) []string {
+	scripts := make([]string, 0)
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+
+		for name, table := range homographScripts {
+			if unicode.Is(table, r) && !slices.Contains(scripts, name) {
+				scripts = append(scripts, name)
+			}
+		}
+	}
+
+	slices.Sort(scripts)
+	return scripts
+}
+
+func isJapanese(scripts []string) bool {
+	for _, script := range scripts {
+		if script != "Han" && script != "Hiragana" && script != "Katakana" && script != "Latin" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizeDomains returns the lowercased ASCII form of the given domain patterns
+func normalizeDomains(domains []string) ([]string, error) {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		if domain == "*" {
+			normalized = append(normalized, domain)
+			continue
+		}
+
+		wildcard := strings.HasPrefix(domain, "*.")
+
+		ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(domain, "*.")), "."))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid domain %s", domain)
+		}
+
+		if wildcard {
+			ascii = "*." + ascii
+		}
+
+		normalized = append(normalized, ascii)
+	}
+
+	return normalized, nil
+}
+
+// matchesAny returns true if the domain matches one of the patterns. A pattern such as example.com only matches
+// the domain itself, *.example.com matches all of its subdomains, and * matches every domain.
+func matchesAny(domain string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if suffix, wildcard := strings.CutPrefix(pattern, "*"); wildcard {
+			if suffix == "" || strings.HasSuffix(domain, suffix) {
+				return true
+			}
+		} else if domain == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,208 @@
+package policy
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/spechtlabs/urlshortener/api/v1alpha1"
+)
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		target  string
+		wantErr string
+	}{
+		{name: "plain target", target: "https://example.com/docs"},
+		{name: "target without scheme", target: "example.com/docs"},
+		{name: "templated path", target: "https://github.com/{1}/{2}?tab={tab}"},
+
+		{name: "javascript scheme", target: "javascript:alert(1)", wantErr: "scheme javascript is not allowed"},
+		{name: "upper case scheme", target: "JavaScript:alert(1)", wantErr: "scheme javascript is not allowed"},
+		{name: "scheme split by a tab", target: "java\tscript:alert(1)", wantErr: "scheme javascript is not allowed"},
+		{name: "scheme split by a newline", target: "java\nscript:alert(1)", wantErr: "scheme javascript is not allowed"},
+		{name: "leading whitespace", target: " \tjavascript:alert(1)", wantErr: "scheme javascript is not allowed"},
+		{name: "data scheme", target: "data:text/html,<script>alert(1)</script>", wantErr: "scheme data is not allowed"},
+		{name: "file scheme", target: "file:///etc/passwd", wantErr: "scheme file is not allowed"},
+		{name: "configured schemes replace the defaults", config: Config{BlockedSchemes: []string{"ftp:"}}, target: "ftp://example.com", wantErr: "scheme ftp is not allowed"},
+
+		{name: "localhost", target: "http://localhost:8080", wantErr: "private host"},
+		{name: "subdomain of localhost", target: "http://app.localhost", wantErr: "private host"},
+		{name: "loopback", target: "http://127.0.0.1/", wantErr: "private host"},
+		{name: "private network", target: "http://10.0.0.1/", wantErr: "private host"},
+		{name: "carrier-grade NAT", target: "http://100.64.0.1/", wantErr: "private host"},
+		{name: "link-local metadata service", target: "http://169.254.169.254/latest/meta-data", wantErr: "private host"},
+		{name: "unspecified", target: "http://0.0.0.0/", wantErr: "private host"},
+		{name: "this network", target: "http://0.1.2.3/", wantErr: "private host"},
+		{name: "decimal loopback", target: "http://2130706433/", wantErr: "private host"},
+		{name: "octal loopback", target: "http://0177.0.0.1/", wantErr: "private host"},
+		{name: "hex loopback", target: "http://0x7f.0.0.1/", wantErr: "private host"},
+		{name: "hex loopback as single number", target: "http://0x7f000001/", wantErr: "private host"},
+		{name: "short loopback", target: "http://127.1/", wantErr: "private host"},
+		{name: "short private network", target: "http://10.1/", wantErr: "private host"},
+		{name: "trailing dot", target: "http://127.0.0.1./", wantErr: "private host"},
+		{name: "IPv6 loopback", target: "http://[::1]/", wantErr: "private host"},
+		{name: "IPv4-mapped IPv6 loopback", target: "http://[::ffff:127.0.0.1]/", wantErr: "private host"},
+		{name: "NAT64 loopback", target: "http://[64:ff9b::7f00:1]/", wantErr: "private host"},
+		{name: "IPv6 unique local", target: "http://[fd00::1]/", wantErr: "private host"},
+		{name: "out of range IPv4 address", target: "http://256.0.0.1/", wantErr: "invalid IPv4 address"},
+		{name: "too many IPv4 parts", target: "http://1.2.3.4.5/", wantErr: "invalid IPv4 address"},
+		{name: "public IP address", target: "http://93.184.215.14/"},
+		{name: "public decimal IP address", target: "http://1572394766/"},
+		{name: "domain starting with digits", target: "https://1password.com"},
+		{name: "private IPs allowed", config: Config{AllowPrivateIPs: true}, target: "http://0x7f.1/"},
+
+		{name: "allowed domain", config: Config{AllowedDomains: []string{"example.com"}}, target: "https://example.com/docs"},
+		{name: "subdomain of an exactly allowed domain", config: Config{AllowedDomains: []string{"example.com"}}, target: "https://docs.example.com", wantErr: "is not allowed"},
+		{name: "wildcard allows subdomains", config: Config{AllowedDomains: []string{"*.example.com"}}, target: "https://docs.example.com"},
+		{name: "wildcard requires a subdomain", config: Config{AllowedDomains: []string{"*.example.com"}}, target: "https://example.com", wantErr: "is not allowed"},
+		{name: "wildcard does not match a suffix", config: Config{AllowedDomains: []string{"*.example.com"}}, target: "https://evilexample.com", wantErr: "is not allowed"},
+		{name: "star allows every domain", config: Config{AllowedDomains: []string{"*"}}, target: "https://example.org"},
+		{name: "blocked domain", config: Config{BlockedDomains: []string{"evil.example"}}, target: "https://EVIL.example./", wantErr: "is blocked"},
+		{name: "blocked wildcard", config: Config{BlockedDomains: []string{"*.evil.example"}}, target: "https://www.evil.example", wantErr: "is blocked"},
+		{name: "block wins over allow", config: Config{AllowedDomains: []string{"*.example.com"}, BlockedDomains: []string{"admin.example.com"}}, target: "https://admin.example.com", wantErr: "is blocked"},
+		{name: "blocked IP address in another notation", config: Config{BlockedDomains: []string{"93.184.215.14"}}, target: "http://1572394766/", wantErr: "is blocked"},
+		{name: "internationalized domain in either form", config: Config{AllowedDomains: []string{"bücher.example"}}, target: "https://xn--bcher-kva.example"},
+
+		{name: "Cyrillic letter in a Latin domain", target: "https://аpple.com", wantErr: "mixes the scripts"},
+		{name: "punycode of a mixed domain", target: "https://xn--pple-43d.com", wantErr: "mixes the scripts"},
+		{name: "Cyrillic domain under a Latin top-level domain", target: "https://аррӏе.com", wantErr: "Cyrillic letters under a Latin top-level domain"},
+		{name: "Cyrillic domain under a Cyrillic top-level domain", target: "https://пример.рф"},
+		{name: "Japanese domain", target: "https://日本語のドメイン.jp"},
+		{name: "homographs allowed", config: Config{AllowHomographs: true}, target: "https://аpple.com"},
+
+		{name: "placeholder host", target: "https://{1}/", wantErr: "must not contain placeholders"},
+		{name: "placeholder host without scheme", target: "{1}", wantErr: "must not contain placeholders"},
+		{name: "placeholder subdomain", target: "https://{1}.example.com/", wantErr: "must not contain placeholders"},
+		{name: "placeholder port", target: "https://example.com:{port}/", wantErr: "not a valid URL"},
+		{name: "placeholder host with allowlist", config: Config{AllowedDomains: []string{"*"}}, target: "https://{host}/", wantErr: "must not contain placeholders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := New(tt.config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			err = policy.CheckTarget(tt.target)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckTarget(%q) error = %v, want nil", tt.target, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckTarget(%q) error = %v, want %q", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseHostIP(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{host: "127.0.0.1", want: "127.0.0.1"},
+		{host: "2130706433", want: "127.0.0.1"},
+		{host: "0177.0.0.01", want: "127.0.0.1"},
+		{host: "0x7f.0x0.0.0x1", want: "127.0.0.1"},
+		{host: "0X7F000001", want: "127.0.0.1"},
+		{host: "127.1", want: "127.0.0.1"},
+		{host: "127.0.1", want: "127.0.0.1"},
+		{host: "192.168.257", want: "192.168.1.1"},
+		{host: "0x", want: "0.0.0.0"},
+		{host: "::ffff:7f00:1", want: "127.0.0.1"},
+		{host: "[::1]", want: "::1"},
+		{host: "example.com"},
+		{host: "1password.com"},
+		{host: "4294967296", wantErr: true},
+		{host: "127.256.0.1", wantErr: true},
+		{host: "127.0.65536", wantErr: true},
+		{host: "08.0.0.1", wantErr: true},
+		{host: "example.123", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			ip, err := parseHostIP(tt.host)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseHostIP() = %v, want an error", ip)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseHostIP() error = %v", err)
+			}
+
+			got := ""
+			if ip != nil {
+				got = ip.String()
+			}
+
+			if got != tt.want {
+				t.Errorf("parseHostIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"100.64.0.1":       true,
+		"100.127.255.255":  true,
+		"169.254.169.254":  true,
+		"0.0.0.0":          true,
+		"::":               true,
+		"::1":              true,
+		"fe80::1":          true,
+		"fd00::1":          true,
+		"::ffff:10.0.0.1":  true,
+		"64:ff9b::a00:1":   true,
+		"100.128.0.1":      false,
+		"8.8.8.8":          false,
+		"2606:4700::1111":  false,
+		"64:ff9b::808:808": false,
+	} {
+		if got := IsPrivateIP(net.ParseIP(address)); got != want {
+			t.Errorf("IsPrivateIP(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	shortlink := &v1alpha1.Shortlink{
+		Spec: v1alpha1.ShortlinkSpec{
+			Target:         "https://example.com/target",
+			NoArgsTarget:   "https://example.com/no-args",
+			FallbackTarget: "https://example.com/fallback",
+			Schedule:       []v1alpha1.ScheduleEntry{{Target: "https://example.com/schedule"}},
+			Rules:          []v1alpha1.TargetRule{{Expression: "true", Target: "https://example.com/rule"}},
+			Variants:       []v1alpha1.TargetVariant{{Name: "a", Target: "https://example.com/variant", Weight: 1}},
+		},
+	}
+
+	want := []string{
+		"https://example.com/target",
+		"https://example.com/no-args",
+		"https://example.com/fallback",
+		"https://example.com/schedule",
+		"https://example.com/rule",
+		"https://example.com/variant",
+	}
+
+	if got := Targets(shortlink); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Targets() = %v, want %v", got, want)
+	}
+}
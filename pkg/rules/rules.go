@@ -0,0 +1,175 @@
+package rules
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/pkg/errors"
+
+	"k8s.io/apiserver/pkg/cel/library"
+)
+
+// costLimit bounds the runtime cost of a single rule evaluation, so a rule cannot stall the redirect path
+const costLimit = 100_000
+
+// maxPrograms bounds the number of compiled programs an Evaluator caches, so expressions
+// that were edited or removed since do not stay in memory for good
+const maxPrograms = 1024
+
+// Request holds the attributes of an HTTP request that targeting rules can match on
+type Request struct {
+	// Headers are the request headers with lower-case names. Only the first value of each header is kept.
+	Headers map[string]string
+
+	// Language is the primary language subtag of the most preferred Accept-Language entry, e.g. "de"
+	Language string
+
+	// UserAgent is the User-Agent header of the request
+	UserAgent string
+
+	// IP is the client IP of the request
+	IP string
+
+	// Path is the path of the request
+	Path string
+
+	// Query holds the query parameters of the request. Only the first value of each parameter is kept.
+	Query map[string]string
+
+	// Time is the point in time the request was received
+	Time time.Time
+}
+
+// Evaluator compiles CEL targeting rules and evaluates them against requests.
+// Compiled programs are cached by their expression, evicting the least recently used ones.
+type Evaluator struct {
+	env         *cel.Env
+	maxPrograms int
+
+	mu       sync.Mutex
+	programs map[string]*list.Element
+	recent   *list.List
+}
+
+// cachedProgram is an entry of the program cache, which lists the most recently used entries first
+type cachedProgram struct {
+	expression string
+	program    cel.Program
+}
+
+// NewEvaluator creates a new Evaluator.
+//
+// Rules can use the variables headers, language, userAgent, ip, path, query and now,
+// as well as the Kubernetes ip() and cidr() functions, e.g. cidr('10.0.0.0/8').containsIP(ip)
+func NewEvaluator() (*Evaluator, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("language", cel.StringType),
+		cel.Variable("userAgent", cel.StringType),
+		cel.Variable("ip", cel.StringType),
+		cel.Variable("path", cel.StringType),
+		cel.Variable("query", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("now", cel.TimestampType),
+		library.IP(),
+		library.CIDR(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create CEL environment")
+	}
+
+	return &Evaluator{
+		env:         env,
+		maxPrograms: maxPrograms,
+		programs:    make(map[string]*list.Element),
+		recent:      list.New(),
+	}, nil
+}
+
+// Compile checks and compiles a rule expression. It returns an error if the expression is invalid
+// or does not evaluate to a bool.
+func (e *Evaluator) Compile(expression string) (cel.Program, error) {
+	if program, ok := e.cachedProgram(expression); ok {
+		return program, nil
+	}
+
+	ast, issues := e.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, errors.Wrap(issues.Err(), "Unable to compile rule expression")
+	}
+
+	if ast.OutputType() != cel.BoolType {
+		return nil, errors.Errorf("Rule expression must evaluate to bool, not %s", ast.OutputType())
+	}
+
+	program, err := e.env.Program(ast, cel.CostLimit(costLimit))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create program for rule expression")
+	}
+
+	e.cacheProgram(expression, program)
+
+	return program, nil
+}
+
+// cachedProgram returns the cached program of the expression and marks it as recently used
+func (e *Evaluator) cachedProgram(expression string) (cel.Program, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	element, ok := e.programs[expression]
+	if !ok {
+		return nil, false
+	}
+
+	e.recent.MoveToFront(element)
+	return element.Value.(*cachedProgram).program, true
+}
+
+// cacheProgram caches the program of the expression and evicts the least recently used programs beyond maxPrograms
+func (e *Evaluator) cacheProgram(expression string, program cel.Program) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if element, ok := e.programs[expression]; ok {
+		e.recent.MoveToFront(element)
+		return
+	}
+
+	e.programs[expression] = e.recent.PushFront(&cachedProgram{expression: expression, program: program})
+
+	for e.recent.Len() > e.maxPrograms {
+		oldest := e.recent.Back()
+		e.recent.Remove(oldest)
+		delete(e.programs, oldest.Value.(*cachedProgram).expression)
+	}
+}
+
+// Match evaluates a rule expression against a request
+func (e *Evaluator) Match(expression string, request Request) (bool, error) {
+	program, err := e.Compile(expression)
+	if err != nil {
+		return false, err
+	}
+
+	result, _, err := program.Eval(map[string]interface{}{
+		"headers":   request.Headers,
+		"language":  request.Language,
+		"userAgent": request.UserAgent,
+		"ip":        request.IP,
+		"path":      request.Path,
+		"query":     request.Query,
+		"now":       request.Time,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "Unable to evaluate rule expression")
+	}
+
+	matched, ok := result.Value().(bool)
+	if !ok {
+		return false, errors.Errorf("Rule expression evaluated to %s instead of bool", result.Type())
+	}
+
+	return matched, nil
+}
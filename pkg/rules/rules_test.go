@@ -0,0 +1,102 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	evaluator, err := NewEvaluator()
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+
+	request := Request{
+		Headers:   map[string]string{"x-team": "sre"},
+		Language:  "de",
+		UserAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)",
+		IP:        "10.1.2.3",
+		Path:      "/docs/runbook",
+		Query:     map[string]string{"env": "prod"},
+		Time:      time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		want       bool
+		wantErr    string
+	}{
+		{name: "language", expression: `language == "de"`, want: true},
+		{name: "other language", expression: `language == "en"`, want: false},
+		{name: "header", expression: `headers["x-team"] == "sre"`, want: true},
+		{name: "missing header", expression: `"x-other" in headers`, want: false},
+		{name: "user agent", expression: `userAgent.contains("iPhone")`, want: true},
+		{name: "path", expression: `path.startsWith("/docs/")`, want: true},
+		{name: "query", expression: `query["env"] == "prod"`, want: true},
+		{name: "cidr", expression: `cidr("10.0.0.0/8").containsIP(ip)`, want: true},
+		{name: "other cidr", expression: `cidr("192.168.0.0/16").containsIP(ip)`, want: false},
+		{name: "time", expression: `now > timestamp("2025-01-01T00:00:00Z")`, want: true},
+		{name: "invalid syntax", expression: `language ==`, wantErr: "Unable to compile rule expression"},
+		{name: "unknown variable", expression: `country == "DE"`, wantErr: "Unable to compile rule expression"},
+		{name: "non-bool output", expression: `language`, wantErr: "must evaluate to bool"},
+		{name: "evaluation error", expression: `headers["x-missing"] == "sre"`, wantErr: "Unable to evaluate rule expression"},
+		{
+			name:       "cost limit",
+			expression: `[0, 1, 2, 3, 4, 5, 6, 7, 8, 9].all(a, [0, 1, 2, 3, 4, 5, 6, 7, 8, 9].all(b, [0, 1, 2, 3, 4, 5, 6, 7, 8, 9].all(c, [0, 1, 2, 3, 4, 5, 6, 7, 8, 9].all(d, [0, 1, 2, 3, 4, 5, 6, 7, 8, 9].all(e, a + b + c + d + e >= 0)))))`,
+			wantErr:    "cost limit exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluator.Match(tt.expression, request)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Match() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileEvictsLeastRecentlyUsedPrograms(t *testing.T) {
+	evaluator, err := NewEvaluator()
+	if err != nil {
+		t.Fatalf("NewEvaluator() error = %v", err)
+	}
+
+	evaluator.maxPrograms = 2
+
+	for idx := range 3 {
+		if _, err := evaluator.Compile(fmt.Sprintf("path == %q", fmt.Sprint(idx))); err != nil {
+			t.Fatalf("Compile() error = %v", err)
+		}
+
+		// Keep the first expression in use, so the second one is the least recently used
+		if _, err := evaluator.Compile(`path == "0"`); err != nil {
+			t.Fatalf("Compile() error = %v", err)
+		}
+	}
+
+	if len(evaluator.programs) != 2 || evaluator.recent.Len() != 2 {
+		t.Fatalf("cached %d programs, want 2", len(evaluator.programs))
+	}
+
+	for expression, wantCached := range map[string]bool{`path == "0"`: true, `path == "1"`: false, `path == "2"`: true} {
+		if _, cached := evaluator.programs[expression]; cached != wantCached {
+			t.Errorf("program of %s cached = %v, want %v", expression, cached, wantCached)
+		}
+	}
+}
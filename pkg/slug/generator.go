@@ -0,0 +1,134 @@
+package slug
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Strategy defines how random slugs are generated
+type Strategy string
+
+const (
+	// StrategyBase36 generates slugs of random lowercase letters and digits, e.g. az3kq9x.
+	// Shortlinks resolve regardless of case, so uppercase letters would not add to the number of distinct slugs.
+	StrategyBase36 Strategy = "base36"
+	// StrategyBase62 is the former name of StrategyBase36, which is still accepted
+	//
+	// Deprecated: use StrategyBase36
+	StrategyBase62 Strategy = "base62"
+	// StrategyWords generates slugs of random, readable words, e.g. brave-otter-lamp
+	StrategyWords Strategy = "words"
+	// StrategyHash generates slugs from the hash of the target, so the same target gets the same slug first
+	StrategyHash Strategy = "hash"
+)
+
+const base36Alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// Generator generates random slugs for shortlinks that are created without a name
+type Generator struct {
+	strategy Strategy
+	length   int
+}
+
+// NewGenerator creates a new Generator. The length is the number of characters of base36 and hash slugs,
+// and the number of words of words slugs. A length of 0 selects the default of the strategy: 7 characters,
+// about 36 bits or 78 billion slugs, and 3 words.
+func NewGenerator(strategy Strategy, length int) (*Generator, error) {
+	if length < 0 {
+		return nil, fmt.Errorf("invalid slug length %d", length)
+	}
+
+	switch strategy {
+	case StrategyBase36, StrategyBase62, StrategyHash:
+		if strategy == StrategyBase62 {
+			strategy = StrategyBase36
+		}
+
+		if length == 0 {
+			length = 7
+		}
+
+	case StrategyWords:
+		if length == 0 {
+			length = 3
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown slug strategy %q, expected one of %s, %s or %s", strategy, StrategyBase36, StrategyWords, StrategyHash)
+	}
+
+	return &Generator{
+		strategy: strategy,
+		length:   length,
+	}, nil
+}
+
+// Generate returns a new slug for the given target. attempt counts the slugs already rejected
+// because they were taken, so the hash strategy can derive a different slug for every attempt.
+func (g *Generator) Generate(target string, attempt int) (string, error) {
+	switch g.strategy {
+	case StrategyWords:
+		parts := make([]string, 0, g.length)
+		for range g.length {
+			idx, err := randomIndex(len(words))
+			if err != nil {
+				return "", err
+			}
+
+			parts = append(parts, words[idx])
+		}
+
+		return strings.Join(parts, "-"), nil
+
+	case StrategyHash:
+		input := target
+		if attempt > 0 {
+			input = target + "#" + strconv.Itoa(attempt)
+		}
+
+		return base36(sha256.Sum256([]byte(input)), g.length), nil
+
+	default:
+		var slug strings.Builder
+		for range g.length {
+			idx, err := randomIndex(len(base36Alphabet))
+			if err != nil {
+				return "", err
+			}
+
+			slug.WriteByte(base36Alphabet[idx])
+		}
+
+		return slug.String(), nil
+	}
+}
+
+// base36 encodes the leading bytes of a hash as a base36 string of the given length
+func base36(hash [sha256.Size]byte, length int) string {
+	value := new(big.Int).SetBytes(hash[:])
+	base := big.NewInt(int64(len(base36Alphabet)))
+	digit := new(big.Int)
+
+	var slug strings.Builder
+	for range length {
+		value.DivMod(value, base, digit)
+		slug.WriteByte(base36Alphabet[digit.Int64()])
+	}
+
+	return slug.String()
+}
+
+func randomIndex(n int) (int, error) {
+	idx, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, errors.Wrap(err, "Unable to generate random slug")
+	}
+
+	return int(idx.Int64()), nil
+}
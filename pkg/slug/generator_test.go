@@ -0,0 +1,123 @@
+package slug
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNewGenerator(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   Strategy
+		length     int
+		wantLength int
+		wantErr    bool
+	}{
+		{name: "base36 default length", strategy: StrategyBase36, wantLength: 7},
+		{name: "former base62 name", strategy: StrategyBase62, wantLength: 7},
+		{name: "hash default length", strategy: StrategyHash, wantLength: 7},
+		{name: "words default length", strategy: StrategyWords, wantLength: 3},
+		{name: "explicit length", strategy: StrategyBase36, length: 10, wantLength: 10},
+		{name: "negative length", strategy: StrategyBase36, length: -1, wantErr: true},
+		{name: "unknown strategy", strategy: "uuid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generator, err := NewGenerator(tt.strategy, tt.length)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("NewGenerator() = %+v, want an error", generator)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("NewGenerator() error = %v", err)
+			}
+
+			if generator.length != tt.wantLength {
+				t.Errorf("length = %d, want %d", generator.length, tt.wantLength)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run("base36 slugs have the length and alphabet", func(t *testing.T) {
+		generator, _ := NewGenerator(StrategyBase36, 8)
+
+		seen := map[string]bool{}
+		for attempt := range 100 {
+			slug, err := generator.Generate("https://example.com", attempt)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			if len(slug) != 8 || strings.Trim(slug, base36Alphabet) != "" {
+				t.Fatalf("Generate() = %q, want 8 base36 characters", slug)
+			}
+
+			// Shortlinks resolve regardless of case, so generated slugs must not differ only in case
+			if strings.ToLower(slug) != slug {
+				t.Fatalf("Generate() = %q, want lowercase characters", slug)
+			}
+
+			seen[slug] = true
+		}
+
+		if len(seen) < 99 {
+			t.Errorf("Generate() returned only %d different slugs in 100 attempts", len(seen))
+		}
+	})
+
+	t.Run("words slugs join known words", func(t *testing.T) {
+		generator, _ := NewGenerator(StrategyWords, 4)
+
+		slug, err := generator.Generate("https://example.com", 0)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+
+		parts := strings.Split(slug, "-")
+		if len(parts) != 4 {
+			t.Fatalf("Generate() = %q, want 4 words", slug)
+		}
+
+		for _, part := range parts {
+			if !slices.Contains(words, part) {
+				t.Errorf("Generate() = %q contains unknown word %q", slug, part)
+			}
+		}
+
+		if !IsObjectName(slug) {
+			t.Errorf("Generate() = %q, which is not a valid object name", slug)
+		}
+	})
+
+	t.Run("hash slugs are stable per target and attempt", func(t *testing.T) {
+		generator, _ := NewGenerator(StrategyHash, 0)
+
+		first, _ := generator.Generate("https://example.com", 0)
+		again, _ := generator.Generate("https://example.com", 0)
+		retry, _ := generator.Generate("https://example.com", 1)
+		other, _ := generator.Generate("https://example.org", 0)
+
+		if len(first) != 7 || strings.Trim(first, base36Alphabet) != "" {
+			t.Fatalf("Generate() = %q, want 7 base36 characters", first)
+		}
+
+		if first != again {
+			t.Errorf("Generate() = %q and %q for the same target", first, again)
+		}
+
+		if first == retry {
+			t.Errorf("Generate() returned %q again after a collision", retry)
+		}
+
+		if first == other {
+			t.Errorf("Generate() returned %q for different targets", first)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package slug
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// maxPrefixLength is the length the readable part of a derived object name is cut to
+	maxPrefixLength = 40
+
+	// hashLength is the number of hex digits of the slug hash appended to a derived object name
+	hashLength = 10
+
+	// fallbackPrefix is used when nothing of the slug survives sanitizing, e.g. for emoji slugs
+	fallbackPrefix = "shortlink"
+)
+
+// ObjectName derives the name of the Kubernetes object storing the shortlink with the given slug.
+// Slugs that are valid object names already are used as they are. All others are sanitized to a
+// DNS-1123 compatible prefix, followed by a hash of the slug that keeps different slugs apart,
+// e.g. "Team_Docs" becomes "team-docs-1a2b3c4d5e".
+func ObjectName(slug string) string {
+	if IsObjectName(slug) {
+		return slug
+	}
+
+	var prefix strings.Builder
+	dash := false
+
+	// Decomposing the slug splits accented letters into the letter and its combining mark, so Über becomes uber
+	for _, r := range norm.NFD.String(strings.ToLower(slug)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			prefix.WriteRune(r)
+			dash = false
+
+		case !dash && prefix.Len() > 0:
+			prefix.WriteRune('-')
+			dash = true
+		}
+
+		if prefix.Len() >= maxPrefixLength {
+			break
+		}
+	}
+
+	name := strings.Trim(prefix.String(), "-")
+	if name == "" {
+		name = fallbackPrefix
+	}
+
+	hash := sha256.Sum256([]byte(slug))
+	return name + "-" + hex.EncodeToString(hash[:])[:hashLength]
+}
+
+// IsObjectName returns true if the slug is a valid Kubernetes object name
+func IsObjectName(slug string) bool {
+	return len(validation.IsDNS1123Subdomain(slug)) == 0
+}
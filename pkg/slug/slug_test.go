@@ -0,0 +1,37 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectName(t *testing.T) {
+	tests := []struct {
+		name       string
+		slug       string
+		wantPrefix string
+	}{
+		{name: "valid object name is kept", slug: "team-docs", wantPrefix: "team-docs"},
+		{name: "upper case and underscores are sanitized", slug: "Team_Docs", wantPrefix: "team-docs-"},
+		{name: "accents are dropped", slug: "Über", wantPrefix: "uber-"},
+		{name: "emoji fall back to a generic prefix", slug: "🚀", wantPrefix: fallbackPrefix + "-"},
+		{name: "long slugs are cut", slug: strings.Repeat("A", 100), wantPrefix: strings.Repeat("a", maxPrefixLength) + "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ObjectName(tt.slug)
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("ObjectName() = %q, want prefix %q", got, tt.wantPrefix)
+			}
+
+			if !IsObjectName(got) {
+				t.Errorf("ObjectName() = %q, which is not a valid object name", got)
+			}
+		})
+	}
+
+	if ObjectName("Team_Docs") == ObjectName("team-docs") || ObjectName("Team_Docs") == ObjectName("Team Docs") {
+		t.Errorf("ObjectName() maps different slugs to the same object name")
+	}
+}
@@ -0,0 +1,24 @@
+package slug
+
+// words is the list random words slugs are built from. The words are short, lowercase
+// and easy to spell, so slugs built from them can be read out and typed.
+var words = []string{
+	"acorn", "amber", "anchor", "apple", "arrow", "aspen", "atlas", "autumn",
+	"badge", "bamboo", "basil", "beacon", "berry", "birch", "bison", "blaze",
+	"bloom", "brave", "breeze", "brick", "brook", "cabin", "cactus", "candle",
+	"canyon", "cedar", "chalk", "cherry", "cider", "clover", "cobalt", "comet",
+	"coral", "cotton", "crane", "crisp", "crystal", "daisy", "dawn", "delta",
+	"dune", "eagle", "ember", "falcon", "fern", "fiesta", "flint", "forest",
+	"fox", "frost", "garnet", "gentle", "ginger", "glacier", "golden", "granite",
+	"grove", "harbor", "hazel", "heron", "honey", "island", "ivory", "jade",
+	"jasper", "juniper", "kayak", "kettle", "kiwi", "lagoon", "lamp", "lantern",
+	"lemon", "lilac", "linen", "lively", "lotus", "lucky", "lunar", "maple",
+	"marble", "meadow", "mellow", "mint", "misty", "mosaic", "moss", "nectar",
+	"nimble", "noble", "nova", "oasis", "ocean", "olive", "onyx", "orbit",
+	"orchid", "otter", "pebble", "pepper", "pine", "pixel", "plum", "polar",
+	"poppy", "prairie", "quartz", "quiet", "rapid", "raven", "reef", "ripple",
+	"river", "robin", "rocket", "saffron", "sage", "sandy", "sapphire", "shore",
+	"silver", "sky", "solar", "sparrow", "spruce", "stone", "sunny", "swift",
+	"thistle", "thunder", "tidal", "timber", "topaz", "tulip", "tundra", "velvet",
+	"violet", "walnut", "willow", "winter", "wren", "zephyr", "zesty", "zinc",
+}